@@ -30,7 +30,10 @@ var (
 	edit_configTempl      = template.Must(template.ParseFiles("templates/editConfig.html"))
 	delete_configTempl    = template.Must(template.ParseFiles("templates/deleteConfig.html"))
 	feedbackTempl         = template.Must(template.ParseFiles("templates/feedback.html"))
-	reportTempl           = template.Must(template.ParseFiles("templates/report.html"))
+	reportTempl           = template.Must(template.ParseFiles("templates/report.html", "templates/histogram.html"))
+	compareTempl          = template.Must(template.ParseFiles("templates/compare.html"))
+	signaturesTempl       = template.Must(template.ParseFiles("templates/signatures.html"))
+	comparisonTempl       = template.Must(template.ParseFiles("templates/comparison.html"))
 )
 var (
 	project_id           string   = "log-parser-278319"
@@ -38,6 +41,8 @@ var (
 	app_specific_buckets []string = []string{"log-parser-278319.appspot.com", "staging.log-parser-278319.appspot.com", "us.artifacts.log-parser-278319.appspot.com"}
 ) //TODO: Put in a config file later
 var cloudConfigs map[string][]string = make(map[string][]string)
+var analysisStore = report.NewAnalysisStore(10)
+var knownSignatures = report.NewSignatureDB(app_specific_buckets[0])
 var (
 	cfg_edit    string
 	bucket_edit string
@@ -83,6 +88,23 @@ func getFeedBack(err error, content string) {
 		feedBack.Content = content
 	}
 }
+
+// storedReport serves /report/{id}/... from a stored analysis, such as the permalink /report/{id}/raw#L120
+func storedReport(r *http.Request, page string) (*report.FullDetails, bool) {
+	if !strings.HasPrefix(page, "report/") {
+		return nil, false
+	}
+	parts := strings.SplitN(page[len("report/"):], "/", 2)
+	if len(parts) != 2 {
+		return nil, false
+	}
+	details, ok := analysisStore.Get(parts[0])
+	if !ok {
+		return nil, false
+	}
+	r.URL.Path = "/report/" + parts[1]
+	return details, true
+}
 func homeHandler(w http.ResponseWriter, r *http.Request) {
 	page := r.URL.Path[len("/"):]
 	if r.Method != http.MethodPost {
@@ -95,6 +117,19 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
 				edit_config_homeTempl.Execute(w, cloudConfigs)
 			} else if strings.Contains(page, "deleteConfig") {
 				delete_configTempl.Execute(w, cloudConfigs)
+			} else if page == "compare" {
+				fillComparePage(w, r)
+			} else if page == "signatures" {
+				fillSignaturesPage(w, r)
+			} else if page == "report/export" {
+				loadExport(w, r)
+			} else if details, ok := storedReport(r, page); ok {
+				//Stored analyses come from analyseContent, which sets their Config
+				if details.Config == nil {
+					http.Error(w, "The analysis "+details.Analysis_details.Id+" has no config", http.StatusInternalServerError)
+					return
+				}
+				report.LogReport(w, r, details, details.Config)
 			} else {
 				report.LogReport(w, r, &fullLogDetails, &cfg_file)
 			}
@@ -103,18 +138,37 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	//The pages of a permalink post to /report/{id}/..., they are answered from that analysis
+	details := &fullLogDetails
+	if stored, ok := storedReport(r, page); ok {
+		details = stored
+		page = r.URL.Path[len("/"):]
+	}
 	switch page {
 	case "report/events/details":
-		loadEventDetails(w, r, fullLogDetails.Analysis_details.RawLog)
-	case "loglevel":
-		loadLogLevel(w, r, fullLogDetails.Analysis_details.Platform, fullLogDetails.Analysis_details.RawLog)
+		loadEventDetails(w, r, details.Analysis_details.RawLog)
+	case "report/notes":
+		loadNote(w, r)
+	case "report/histogram":
+		loadHistogram(w, r, details.Analysis_details.Histograms)
+	case "report/unknown/issue":
+		loadAddUnknownIssue(w, r, details.Analysis_details.Platform, details.Analysis_details.ConfigName)
 	case "UploadConfig":
 		loadUploadConfig(w, r)
 	case "editConfig":
 		loadEditConfig(w, r)
 	case "deleteConfig":
 		loadDeleteConfig(w, r)
+	case "compare":
+		loadCompare(w, r)
+	case "signatures":
+		loadSignatures(w, r)
 	default:
+		//Such as a /report/{id}/... page of an analysis that is no longer stored
+		if strings.HasPrefix(page, "report/") {
+			http.Error(w, "Unknown page or analysis "+page, http.StatusNotFound)
+			return
+		}
 		loadAnalyseLog(w, r, &fullLogDetails, &cfg_file)
 	}
 
@@ -126,12 +180,6 @@ func fillUploadCfgPage(w http.ResponseWriter, r *http.Request) {
 	}
 	upload_configTempl.Execute(w, bucketList)
 }
-func loadLogLevel(w http.ResponseWriter, r *http.Request, platform string, rawlog string) {
-	r.ParseMultipartForm(10 << 20)
-	level := r.FormValue("selectedLevel")
-	logContent := report.GetLogLeveldetails(platform, level, rawlog)
-	w.Write([]byte(logContent))
-}
 func loadUploadConfig(w http.ResponseWriter, r *http.Request) {
 	configs, err := settings.UploadConfigFile(r, project_id, cloudConfigs)
 	getFeedBack(err, "Upload Config")
@@ -158,6 +206,13 @@ func loadEditConfig(w http.ResponseWriter, r *http.Request) {
 		edit_configTempl.Execute(w, content)
 	}
 }
+func loadAddUnknownIssue(w http.ResponseWriter, r *http.Request, bucket string, cfgfile string) {
+	r.ParseMultipartForm(10 << 20)
+	issue_regex := report.TemplateRegex(r.FormValue("Template"))
+	err := settings.AddConfigIssue(bucket, cfgfile, r.FormValue("IssueName"), issue_regex)
+	getFeedBack(err, "Add Issue")
+	feedbackTempl.Execute(w, feedBack)
+}
 func loadDeleteConfig(w http.ResponseWriter, r *http.Request) {
 	configs, err := settings.DeleteConfig(r, project_id, region_id, cloudConfigs)
 	getFeedBack(err, "Delete Config")
@@ -173,13 +228,84 @@ func loadAnalyseLog(w http.ResponseWriter, r *http.Request, fullDetails *report.
 		feedbackTempl.Execute(w, feedBack)
 		return
 	}
+	report.MatchSignatures(&fullLogDetails, knownSignatures)
+	analysisStore.Add(fullLogDetails)
 	reportTempl.Execute(w, fullLogDetails.Analysis_details)
 }
+
+// fillSignaturesPage lists the known signatures, the form is prefilled by /signatures?issue=name&fields=value
+func fillSignaturesPage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	draft := report.KnownSignature{Issue: query.Get("issue"), Fields: query["fields"], Stack: query.Get("stack")}
+	signaturesTempl.Execute(w, report.NewSignaturesPage(knownSignatures, draft))
+}
+func loadSignatures(w http.ResponseWriter, r *http.Request) {
+	r.ParseMultipartForm(10 << 20)
+	var err error
+	if r.FormValue("action") == "delete" {
+		err = knownSignatures.Delete(r.FormValue("key"))
+	} else {
+		signature := report.KnownSignature{
+			Issue:   r.FormValue("issue"),
+			Stack:   r.FormValue("stack"),
+			Bug:     r.FormValue("bug"),
+			Url:     r.FormValue("url"),
+			Status:  r.FormValue("status"),
+			FixedIn: r.FormValue("fixed_in"),
+		}
+		if fields := strings.Replace(r.FormValue("fields"), "\r\n", "\n", -1); fields != "" {
+			signature.Fields = strings.Split(fields, "\n")
+		}
+		err = knownSignatures.Add(signature)
+	}
+	if err != nil {
+		getFeedBack(err, "Known signatures")
+		feedbackTempl.Execute(w, feedBack)
+		return
+	}
+	//The current and the stored analyses show the new signatures on their next page
+	report.MatchSignatures(&fullLogDetails, knownSignatures)
+	for _, analysis := range analysisStore.List() {
+		analysisStore.Update(analysis.Id, func(details *report.FullDetails) {
+			report.MatchSignatures(details, knownSignatures)
+		})
+	}
+	http.Redirect(w, r, "/signatures", http.StatusSeeOther)
+}
+func fillComparePage(w http.ResponseWriter, r *http.Request) {
+	compareTempl.Execute(w, struct {
+		Sides    []string
+		Configs  map[string][]string
+		Analyses []report.AnalysisDetails
+	}{
+		[]string{"Base", "Target"},
+		cloudConfigs,
+		analysisStore.List(),
+	})
+}
+func loadCompare(w http.ResponseWriter, r *http.Request) {
+	comparison, err := report.CompareLogs(w, r, project_id, region_id, analysisStore)
+	if err != nil {
+		getFeedBack(err, "Log Comparison Error")
+		feedbackTempl.Execute(w, feedBack)
+		return
+	}
+	comparisonTempl.Execute(w, comparison)
+}
 func loadEventDetails(w http.ResponseWriter, r *http.Request, rawlog string) {
 	r.ParseMultipartForm(10 << 20)
 	startIndex, _ := strconv.Atoi(r.FormValue("StartIndex"))
 	endIndex, _ := strconv.Atoi(r.FormValue("EndIndex"))
 	logs := strings.Split(rawlog, "\n")
+	if startIndex < 0 {
+		startIndex = 0
+	}
+	if endIndex >= len(logs) {
+		endIndex = len(logs) - 1
+	}
+	if startIndex > endIndex {
+		startIndex = endIndex + 1
+	}
 	type Reponse struct {
 		Content string
 	}
@@ -191,3 +317,45 @@ func loadEventDetails(w http.ResponseWriter, r *http.Request, rawlog string) {
 	jsonValue, _ := json.Marshal(resp)
 	w.Write(jsonValue)
 }
+func loadExport(w http.ResponseWriter, r *http.Request) {
+	details := &fullLogDetails
+	if id := r.FormValue("id"); id != "" {
+		stored, ok := analysisStore.Get(id)
+		if !ok {
+			http.Error(w, "The analysis "+id+" is no longer available", http.StatusNotFound)
+			return
+		}
+		details = stored
+	}
+	if err := report.ExportReport(w, details, r.FormValue("format"), r.FormValue("table")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// loadNote adds or deletes a note of the analysis picked by id, the current one by default
+func loadNote(w http.ResponseWriter, r *http.Request) {
+	details := &fullLogDetails
+	if id := r.FormValue("id"); id != "" {
+		stored, ok := analysisStore.Get(id)
+		if !ok {
+			http.Error(w, "The analysis "+id+" is no longer available", http.StatusNotFound)
+			return
+		}
+		details = stored
+	}
+	report.NoteRequest(w, r, details)
+}
+func loadHistogram(w http.ResponseWriter, r *http.Request, histograms map[string]report.Histogram) {
+	r.ParseMultipartForm(10 << 20)
+	issue := r.FormValue("Issue")
+	if issue != "" {
+		histogram, ok := histograms[issue]
+		if !ok {
+			http.Error(w, "No occurrence histogram for "+issue, http.StatusNotFound)
+			return
+		}
+		histograms = map[string]report.Histogram{issue: histogram}
+	}
+	jsonValue, _ := json.Marshal(histograms)
+	w.Write(jsonValue)
+}
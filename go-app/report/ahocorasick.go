@@ -0,0 +1,84 @@
+package report
+
+// ahoCorasick finds every literal of a set in one pass over each line
+type ahoCorasick struct {
+	transitions []int32
+	outputs     [][]int
+	lengths     []int
+	prefix      []bool
+}
+
+// literalScan lists, for every literal, the indexes of the lines containing it
+type literalScan struct {
+	lines []string
+	hits  [][]int
+}
+
+func newAhoCorasick(literals []string, prefix []bool) *ahoCorasick {
+	ac := &ahoCorasick{
+		transitions: make([]int32, 256),
+		outputs:     [][]int{nil},
+		lengths:     make([]int, len(literals)),
+		prefix:      prefix,
+	}
+	//Build the trie, 0 means no transition until the failure links are computed
+	for id, literal := range literals {
+		ac.lengths[id] = len(literal)
+		state := int32(0)
+		for i := 0; i < len(literal); i++ {
+			next := ac.transitions[int(state)*256+int(literal[i])]
+			if next == 0 {
+				next = int32(len(ac.outputs))
+				ac.transitions = append(ac.transitions, make([]int32, 256)...)
+				ac.outputs = append(ac.outputs, nil)
+				ac.transitions[int(state)*256+int(literal[i])] = next
+			}
+			state = next
+		}
+		ac.outputs[state] = append(ac.outputs[state], id)
+	}
+	//Turn the trie into a complete automaton with a breadth first walk
+	fail := make([]int32, len(ac.outputs))
+	queue := []int32{}
+	for c := 0; c < 256; c++ {
+		if next := ac.transitions[c]; next != 0 {
+			queue = append(queue, next)
+		}
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		ac.outputs[state] = append(ac.outputs[state], ac.outputs[fail[state]]...)
+		for c := 0; c < 256; c++ {
+			next := ac.transitions[int(state)*256+c]
+			if next != 0 {
+				fail[next] = ac.transitions[int(fail[state])*256+c]
+				queue = append(queue, next)
+			} else {
+				ac.transitions[int(state)*256+c] = ac.transitions[int(fail[state])*256+c]
+			}
+		}
+	}
+	return ac
+}
+func (ac *ahoCorasick) scanLines(lines []string) *literalScan {
+	scan := &literalScan{lines: lines, hits: make([][]int, len(ac.lengths))}
+	last_line := make([]int, len(ac.lengths))
+	for id := range last_line {
+		last_line[id] = -1
+	}
+	for index, line := range lines {
+		state := int32(0)
+		for i := 0; i < len(line); i++ {
+			state = ac.transitions[int(state)*256+int(line[i])]
+			for _, id := range ac.outputs[state] {
+				if last_line[id] == index || (ac.prefix[id] && i+1 != ac.lengths[id]) {
+					continue
+				}
+				last_line[id] = index
+				scan.hits[id] = append(scan.hits[id], index)
+			}
+		}
+	}
+	return scan
+}
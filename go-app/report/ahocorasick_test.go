@@ -0,0 +1,74 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAhoCorasickScan(t *testing.T) {
+	tests := []struct {
+		name     string
+		literals []string
+		prefix   []bool
+		lines    []string
+		hits     [][]int
+	}{
+		{
+			name:     "overlapping literals",
+			literals: []string{"he", "she", "his", "hers"},
+			prefix:   []bool{false, false, false, false},
+			lines:    []string{"ushers", "this", "hershe", "h"},
+			hits:     [][]int{{0, 2}, {0, 2}, {1}, {0, 2}},
+		},
+		{
+			name:     "prefix against contains",
+			literals: []string{"WifiService", "WifiService"},
+			prefix:   []bool{true, false},
+			lines:    []string{"WifiService: connect", "E WifiService: scan", "Wifi", "WifiServiceWifiService"},
+			hits:     [][]int{{0, 3}, {0, 1, 3}},
+		},
+		{
+			name:     "prefix reached by a failure link",
+			literals: []string{"abc", "bc"},
+			prefix:   []bool{false, true},
+			lines:    []string{"abc", "bcd", "xbc", "bcbc"},
+			hits:     [][]int{{0}, {1, 3}},
+		},
+		{
+			name:     "literal found twice in a line",
+			literals: []string{"aa"},
+			prefix:   []bool{false},
+			lines:    []string{"aaaa", "a", "", "baab"},
+			hits:     [][]int{{0, 3}},
+		},
+	}
+	for _, test := range tests {
+		scan := newAhoCorasick(test.literals, test.prefix).scanLines(test.lines)
+		for id, literal := range test.literals {
+			if !reflect.DeepEqual(scan.hits[id], test.hits[id]) {
+				t.Errorf("%s: %q (prefix %v) found on %v, expected %v", test.name, literal, test.prefix[id], scan.hits[id], test.hits[id])
+			}
+		}
+	}
+}
+
+// The literals of any_of, contains and prefix find a line when any one of them is in it
+func TestLiteralMatcherCandidates(t *testing.T) {
+	cfg := testConfig(t, `
+Issues:
+  AnyOf:
+    any_of: [FATAL EXCEPTION, ANR in]
+  Prefixed:
+    prefix: '10-19 14:00:0'
+  Both:
+    contains: ANR in
+    prefix: '10-19 14:00:0'
+`)
+	log := splitLines("literals.txt", "10-19 14:00:01.000 E AndroidRuntime: FATAL EXCEPTION: main\n10-19 14:00:12.000 E ActivityManager: ANR in com.example.app\n10-19 14:00:23.000 I ActivityManager: Start proc\n10-19 14:00:04.000 I FATAL EXCEPTION")
+	scan := cfg.matchers.scan(log)
+	for issue_name, lines := range map[string][]int{"AnyOf": {0, 1, 3}, "Prefixed": {0, 3}, "Both": {0, 1, 3}} {
+		if candidates := cfg.matchers.issues[issue_name].regex.candidates(scan); !reflect.DeepEqual(candidates, lines) {
+			t.Errorf("%s: lines %v, expected %v", issue_name, candidates, lines)
+		}
+	}
+}
@@ -2,44 +2,81 @@ package report
 
 import (
 	"net/http"
-	"reflect"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 type Config struct {
-	SpecificProcess     map[string]string
+	SpecificProcess     map[string]Matcher
 	IssuesGeneralFields struct {
 		Number      string
 		Details     string
 		Timestamp   string
 		Log_level   string
+		Pid         string
+		Tid         string
+		Tag         string
 		OtherFields map[string]string
 	}
 	Issues          map[string]Issue
 	Priority        map[string]int
-	ImportantEvents map[string]string
+	ImportantEvents map[string]ImportantEvent
+	Lifecycle       map[string][]string
+	Redaction       struct {
+		Disabled []string
+		Patterns map[string]string
+	}
+	LogLevels struct {
+		Pattern string
+		Levels  []LogLevel
+		Errors  []string
+	}
+	StackTraces StackTraceConfig
+	matchers    *matcherSet
 }
 
 type ConfigInterface struct {
-	SpecificProcess     map[string]string `yaml:"SpecificProcess"`
+	SpecificProcess     map[string]interface{} `yaml:"SpecificProcess"`
 	IssuesGeneralFields struct {
 		Number      string            `yaml:"Number"`
 		Details     string            `yaml:"Details"`
 		Timestamp   string            `yaml:"Timestamp"`
 		Log_level   string            `yaml:"LogLevel"`
+		Pid         string            `yaml:"Pid"`
+		Tid         string            `yaml:"Tid"`
+		Tag         string            `yaml:"Tag"`
 		OtherFields map[string]string `yaml:"OtherFields"`
 	} `yaml:"IssuesGeneralFields"`
 	Issues          map[string]interface{} `yaml:"Issues"`
 	Priority        map[string]int         `yaml:"Priority"`
-	ImportantEvents map[string]string      `yaml:"ImportantEvents"`
+	ImportantEvents map[string]interface{} `yaml:"ImportantEvents"`
+	Lifecycle       map[string][]string    `yaml:"Lifecycle"`
+	Redaction       struct {
+		Disabled []string          `yaml:"Disabled"`
+		Patterns map[string]string `yaml:"Patterns"`
+	} `yaml:"Redaction"`
+	LogLevels struct {
+		Pattern string     `yaml:"Pattern"`
+		Levels  []LogLevel `yaml:"Levels"`
+		Errors  []string   `yaml:"Errors"`
+	} `yaml:"LogLevels"`
+	StackTraces StackTraceConfig `yaml:"StackTraces"`
 }
 type Issue struct {
-	specific_process  map[string]string
+	specific_process  map[string]Matcher
 	regex             string
+	contains          []string
+	prefix            []string
+	min_count         int
+	max_rate          string
+	rate_window       string
+	context_before    int
+	context_after     int
+	context_window    string
 	detailing_mode    string
 	grouping          string
 	additional_fields map[string]string
@@ -48,21 +85,37 @@ type GroupedStruct struct {
 	Group_names   []string
 	Group_content map[string][][]string
 	Group_count   map[string][]int
+	Group_refs    map[string][][]LineRef
 }
 type AnalysisDetails struct {
+	Id              string
 	FileName        string
 	RawLog          string
 	SpecificProcess map[string]string
 	Header          []string
 	OrderedIssues   []string
 	Issues          map[string]map[string]string
+	Histograms      map[string]Histogram
+	UnknownErrors   []UnknownError
+	StackTraces     []StackTrace
+	Anrs            []AnrSummary
+	Tombstones      []Tombstone
+	Timeline        Timeline
+	Redactions      []Redaction
+	LogLevels       []string
+	Notes           *Notes
+	Known           KnownReport
 	Platform        string
+	ConfigName      string
 }
 type FullDetails struct {
 	Analysis_details AnalysisDetails
 	GroupedIssues    map[string]GroupedStruct
-	NonGroupedIssues map[string]map[string]bool
-	ImportantEvents  map[int]string
+	NonGroupedIssues map[string][]LineRef
+	ImportantEvents  map[int][]EventHit
+	KnownRows        map[string]KnownSignature
+	//Config is the config the log was analysed with, stored analyses are rendered with it
+	Config *Config
 }
 
 func AnalyseLog(w http.ResponseWriter, r *http.Request, project_id string, region_id string, fullLogDetails *FullDetails, cfgFile *Config) error {
@@ -71,21 +124,36 @@ func AnalyseLog(w http.ResponseWriter, r *http.Request, project_id string, regio
 		return err
 	}
 	fullLogDetails.Analysis_details = AnalysisDetails{}
-	//Set the selected platform
-	fullLogDetails.Analysis_details.Platform = bucket
 	err = extractConfig(cfgName, bucket, cfgFile)
 	if err != nil {
 		return err
 	}
+	analyseContent(fullLogDetails, cfgFile, fContent, *fName, cfgName, bucket)
+	return nil
+}
+func analyseContent(fullLogDetails *FullDetails, cfgFile *Config, fContent string, fName string, cfgName string, bucket string) {
+	fullLogDetails.Analysis_details = AnalysisDetails{}
+	fullLogDetails.Analysis_details.Id = newAnalysisId()
+	fullLogDetails.Analysis_details.Notes = newNotes()
+	//Set the selected platform
+	fullLogDetails.Analysis_details.Platform = bucket
+	fullLogDetails.Analysis_details.ConfigName = cfgName
+	config := *cfgFile
+	fullLogDetails.Config = &config
+	//Personal data is replaced before the log is analysed, stored or rendered
+	fContent, fullLogDetails.Analysis_details.Redactions = redactLog(cfgFile.matchers.redactors, fContent)
 	fullLogDetails.GroupedIssues = make(map[string]GroupedStruct)
-	fullLogDetails.NonGroupedIssues = make(map[string]map[string]bool)
-	fullLogDetails.Analysis_details.FileName = *fName
+	fullLogDetails.NonGroupedIssues = make(map[string][]LineRef)
+	fullLogDetails.Analysis_details.FileName = fName
 	fullLogDetails.Analysis_details.RawLog = fContent
 	fullLogDetails.Analysis_details.SpecificProcess = make(map[string]string)
 	spec_proc_map := fullLogDetails.Analysis_details.SpecificProcess
-	setSpecProcessLogs(cfgFile, fContent, spec_proc_map)
+	log := splitLines(fName, fContent)
+	fullLogDetails.Analysis_details.LogLevels = levelNames(cfgFile.matchers, log)
+	log_scan := cfgFile.matchers.scan(log)
+	proc_lines := setSpecProcessLogs(cfgFile, log, log_scan, spec_proc_map)
 	//Fill the header with general fields
-	headerMap := map[string]bool{"Issue": true, "Number": true, "Details": true, "Timestamp": true, "LogLevel": true}
+	headerMap := map[string]bool{"Issue": true, "Status": true, "Number": true, "Details": true, "Timestamp": true, "LogLevel": true, "Occurrences": true}
 	for field, _ := range cfgFile.IssuesGeneralFields.OtherFields {
 		headerMap[field] = true
 	}
@@ -93,25 +161,41 @@ func AnalyseLog(w http.ResponseWriter, r *http.Request, project_id string, regio
 	issues_map := fullLogDetails.Analysis_details.Issues
 	grp_issues := fullLogDetails.GroupedIssues
 	ngrp_issues := fullLogDetails.NonGroupedIssues
-	getIssueDetails(cfgFile, fContent, headerMap, issues_map, spec_proc_map, grp_issues, ngrp_issues)
+	issues_times := make(map[string][]time.Time)
+	claimed_logs := make(map[int]bool)
+	getIssueDetails(cfgFile, log, log_scan, headerMap, issues_map, proc_lines, grp_issues, ngrp_issues, issues_times, claimed_logs)
+	fullLogDetails.Analysis_details.Histograms = buildHistograms(cfgFile, fContent, issues_times)
+	fullLogDetails.Analysis_details.UnknownErrors = clusterUnknownErrors(cfgFile, log, claimed_logs)
+	fullLogDetails.Analysis_details.Timeline = buildTimeline(cfgFile.matchers, log)
+	fullLogDetails.Analysis_details.StackTraces = extractStackTraces(cfgFile.matchers, log)
+	fullLogDetails.Analysis_details.Anrs = parseAnrs(log)
+	fullLogDetails.Analysis_details.Tombstones = parseTombstones(cfgFile.matchers, log)
+	//The events are found once, the events pages of a stored analysis only read them
+	fullLogDetails.ImportantEvents = make(map[int][]EventHit)
+	getImportantEvents(cfgFile, fContent, fullLogDetails.ImportantEvents)
+	evaluateThresholds(cfgFile, fContent, issues_map, issues_times, headerMap)
 	fullLogDetails.Analysis_details.OrderedIssues = make([]string, len(cfgFile.Issues), len(cfgFile.Issues))
-	sortIssue(cfgFile, fullLogDetails.Analysis_details.OrderedIssues)
+	sortIssue(cfgFile, issues_map, fullLogDetails.Analysis_details.OrderedIssues)
 	fullLogDetails.Analysis_details.Header = fillHeader(headerMap)
-	return nil
 }
-func sortIssue(cfgFile *Config, issues []string) {
+func sortIssue(cfgFile *Config, issues_map map[string]map[string]string, issues []string) {
 	index := 0
 	for k := range cfgFile.Issues {
 		issues[index] = k
 		index++
 	}
+	//Triggered issues come first, then the ones that could not be evaluated, then the ones below their threshold
 	sort.Slice(issues, func(i, j int) bool {
+		rank_i, rank_j := statusRank(issues_map[issues[i]]["Status"]), statusRank(issues_map[issues[j]]["Status"])
+		if rank_i != rank_j {
+			return rank_i > rank_j
+		}
 		return cfgFile.Priority[issues[i]] > cfgFile.Priority[issues[j]]
 	})
 }
 func fillHeader(headerMap map[string]bool) []string {
 	header := make([]string, 0, len(headerMap))
-	header = append(header, "Issue", "Number", "Details", "Timestamp", "LogLevel")
+	header = append(header, "Issue", "Status", "Number", "Details", "Timestamp", "LogLevel", "Occurrences")
 	for _, field := range header {
 		headerMap[field] = false
 	}
@@ -122,136 +206,231 @@ func fillHeader(headerMap map[string]bool) []string {
 	}
 	return header
 }
-func setSpecProcessLogs(cfgFile *Config, fContent string, spec_proc_map map[string]string) {
+
+// setSpecProcessLogs fills the logs of every specific process and returns the lines they are made of
+func setSpecProcessLogs(cfgFile *Config, log *logLines, log_scan *literalScan, spec_proc_map map[string]string) map[string][]int {
+	proc_lines := make(map[string][]int)
 	var waitGroup sync.WaitGroup
 	var mutex sync.Mutex
-	waitGroup.Add(len(cfgFile.SpecificProcess))
-	for proc, proc_rgx := range cfgFile.SpecificProcess {
-		go func(proc string, proc_rgx string) {
-			proc_rgx_comp, err := regexp.Compile(proc_rgx)
-			if err != nil {
-				waitGroup.Done()
-				return
-			}
-			proc_content := proc_rgx_comp.FindAllString(fContent, -1)
-			if len(proc_content) > 1 {
+	waitGroup.Add(len(cfgFile.matchers.specific_process))
+	for proc, proc_matcher := range cfgFile.matchers.specific_process {
+		go func(proc string, proc_matcher lineMatcher) {
+			lines := matchedLines(proc_matcher.matchLines(log, nil, log_scan))
+			if len(lines) > 1 {
+				proc_content := make([]string, len(lines))
+				for i, index := range lines {
+					proc_content[i] = log.lines[index]
+				}
 				mutex.Lock()
 				spec_proc_map[proc] = strings.Join(proc_content, "\n")
+				proc_lines[proc] = lines
 				mutex.Unlock()
 			}
 			waitGroup.Done()
-		}(proc, proc_rgx)
+		}(proc, proc_matcher)
 	}
 	waitGroup.Wait()
+	return proc_lines
 }
-func getIssueDetails(cfgFile *Config, fContent string, headerMap map[string]bool, issues_map map[string]map[string]string, spec_proc_map map[string]string, grp_issues map[string]GroupedStruct, ngrp_issues map[string]map[string]bool) {
-	specific_proc_content := make(map[string]string)
+
+// issueResult holds everything computed for one issue, so that issues can be analysed
+// concurrently without sharing state and merged once they are all done
+type issueResult struct {
+	issue_name    string
+	details       map[string]string
+	grouped       bool
+	group_details GroupedStruct
+	matches       []LineRef
+	times         []time.Time
+	claimed_logs  []int
+	header_fields []string
+}
+
+func getIssueDetails(cfgFile *Config, log *logLines, log_scan *literalScan, headerMap map[string]bool, issues_map map[string]map[string]string, proc_lines map[string][]int, grp_issues map[string]GroupedStruct, ngrp_issues map[string][]LineRef, issues_times map[string][]time.Time, claimed_logs map[int]bool) {
+	proc_lines = issueProcessLogs(cfgFile, log, log_scan, proc_lines)
+	results := make([]issueResult, len(cfgFile.Issues))
 	var wg sync.WaitGroup
-	var mutex sync.Mutex
-	var spec_mutex sync.Mutex
 	wg.Add(len(cfgFile.Issues))
+	index := 0
 	for issue_name, issue := range cfgFile.Issues {
-		go func(issue_name string, issue Issue) {
-			issues_map[issue_name] = make(map[string]string)
-			//Filter the logs belonging to the issue specific process
-			issueContent := ""
-			for proc, proc_rgx := range issue.specific_process {
-				proc_issue, ok := spec_proc_map[proc]
-				if !ok {
-					spec_mutex.Lock()
-					proc_issue, ok := specific_proc_content[proc]
-					spec_mutex.Unlock()
-					if !ok {
-						proc_rgx_comp, err := regexp.Compile(proc_rgx)
-						if err != nil {
-							continue
-						}
-						raw_proc_issue := proc_rgx_comp.FindAllString(fContent, -1)
-						proc_issue = strings.Join(raw_proc_issue, "\n")
-						spec_mutex.Lock()
-						specific_proc_content[proc] = proc_issue
-						spec_mutex.Unlock()
-					}
-				}
-				issueContent += proc_issue
-				issueContent += "\n"
-			}
-			mutex.Lock()
+		go func(index int, issue_name string, issue Issue) {
+			defer wg.Done()
+			issue_lines := issueLines(proc_lines, issue.specific_process)
 			if issue.detailing_mode == "group" {
-				groupedDetails := GroupedStruct{}
-				groupedDetails.Group_content = make(map[string][][]string)
-				groupedDetails.Group_count = make(map[string][]int)
-				groupedDetails.Group_names = []string{}
-				groupIssueDetails(issue, cfgFile, headerMap, issueContent, issue_name, issues_map, groupedDetails.Group_content, groupedDetails.Group_count, &groupedDetails.Group_names)
-				grp_issues[issue_name] = groupedDetails
+				results[index] = groupIssueDetails(issue, cfgFile, log, issue_lines, log_scan, issue_name)
 			} else {
-				nongroupIssueDetails(issue, cfgFile, headerMap, issueContent, issue_name, issues_map, ngrp_issues)
+				results[index] = nongroupIssueDetails(issue, cfgFile, log, issue_lines, log_scan, issue_name)
 			}
-			mutex.Unlock()
-			wg.Done()
-		}(issue_name, issue)
+		}(index, issue_name, issue)
+		index++
 	}
 	wg.Wait()
+	for _, result := range results {
+		issues_map[result.issue_name] = result.details
+		if result.grouped {
+			grp_issues[result.issue_name] = result.group_details
+		} else {
+			ngrp_issues[result.issue_name] = result.matches
+		}
+		if result.times != nil {
+			issues_times[result.issue_name] = result.times
+		}
+		for _, index := range result.claimed_logs {
+			claimed_logs[index] = true
+		}
+		for _, field := range result.header_fields {
+			headerMap[field] = true
+		}
+	}
 }
-func groupIssueDetails(issue Issue, cfgFile *Config, headerMap map[string]bool, issueContent string, issue_name string, issues_map map[string]map[string]string, group_content map[string][][]string, group_count map[string][]int, group_names *[]string) {
-	group_rgx, err := regexp.Compile(issue.grouping)
-	if err != nil {
-		return
+
+// issueProcessLogs returns the lines of every process used by an issue, each process is
+// filtered only once even when several issues share it
+func issueProcessLogs(cfgFile *Config, log *logLines, log_scan *literalScan, spec_proc_lines map[string][]int) map[string][]int {
+	proc_lines := make(map[string][]int)
+	proc_matchers := make(map[string]lineMatcher)
+	for _, issue := range cfgFile.matchers.issues {
+		for proc, proc_matcher := range issue.specific_process {
+			if lines, ok := spec_proc_lines[proc]; ok {
+				proc_lines[proc] = lines
+			} else {
+				proc_matchers[proc] = proc_matcher
+			}
+		}
 	}
-	names := group_rgx.SubexpNames()
-	names_p := &names
-	*group_names = *names_p
-	last_matches, issues_count := fillGroupDetails(group_content, group_count, issueContent, group_rgx)
-	issue_map := issues_map[issue_name]
-	issue_map["Number"] += strconv.Itoa(issues_count)
+	var waitGroup sync.WaitGroup
+	var mutex sync.Mutex
+	waitGroup.Add(len(proc_matchers))
+	for proc, proc_matcher := range proc_matchers {
+		go func(proc string, proc_matcher lineMatcher) {
+			defer waitGroup.Done()
+			lines := matchedLines(proc_matcher.matchLines(log, nil, log_scan))
+			mutex.Lock()
+			proc_lines[proc] = lines
+			mutex.Unlock()
+		}(proc, proc_matcher)
+	}
+	waitGroup.Wait()
+	return proc_lines
+}
 
-	timestampRegex, _ := regexp.Compile(cfgFile.IssuesGeneralFields.Timestamp)
-	match := timestampRegex.FindStringSubmatch(last_matches)
-	if len(match) > 0 {
-		issue_map["Timestamp"] = match[0]
+// issueLines merges the lines of the issue specific processes, a line shared by two processes is kept once
+func issueLines(proc_lines map[string][]int, specific_process map[string]Matcher) []int {
+	lines := make(map[int]bool)
+	for proc := range specific_process {
+		for _, index := range proc_lines[proc] {
+			lines[index] = true
+		}
+	}
+	issue_lines := make([]int, 0, len(lines))
+	for index := range lines {
+		issue_lines = append(issue_lines, index)
+	}
+	sort.Ints(issue_lines)
+	return issue_lines
+}
+func groupIssueDetails(issue Issue, cfgFile *Config, log *logLines, issue_lines []int, log_scan *literalScan, issue_name string) issueResult {
+	result := issueResult{issue_name: issue_name, details: make(map[string]string), grouped: true}
+	result.group_details = GroupedStruct{
+		Group_names:   []string{},
+		Group_content: make(map[string][][]string),
+		Group_count:   make(map[string][]int),
+		Group_refs:    make(map[string][][]LineRef),
+	}
+	matchers := cfgFile.matchers.issues[issue_name]
+	group_rgx := matchers.grouping.regex
+	if group_rgx == nil {
+		return result
+	}
+	result.group_details.Group_names = group_rgx.SubexpNames()
+	//Literals narrow the lines the grouping regex runs on
+	group_lines := issue_lines
+	if len(matchers.grouping.literals) > 0 {
+		group_lines = matchedLines(lineMatcher{literals: matchers.grouping.literals}.matchLines(log, issue_lines, log_scan))
+	} else if group_lines == nil {
+		group_lines = make([]int, len(log.lines))
+		for index := range group_lines {
+			group_lines[index] = index
+		}
+	}
+	matched_lines, issues_count := fillGroupDetails(result.group_details, log, group_lines, group_rgx)
+	issue_map := result.details
+	issue_map["Number"] = strconv.Itoa(issues_count)
+	result.claimed_logs = matched_lines
+	matched_logs := make([]string, len(matched_lines))
+	for i, index := range matched_lines {
+		matched_logs[i] = log.lines[index]
+	}
+	last_matches := ""
+	if len(matched_logs) > 0 {
+		last_matches = matched_logs[len(matched_logs)-1]
+	}
+	timestampRegex := cfgFile.matchers.timestamp
+	if timestampRegex != nil {
+		result.times = matchTimes(timestampRegex, matched_logs)
+		match := timestampRegex.FindStringSubmatch(last_matches)
+		if len(match) > 0 {
+			issue_map["Timestamp"] = match[0]
+		}
 	}
 
-	log_rgx, err := regexp.Compile(cfgFile.IssuesGeneralFields.Log_level)
-	if err != nil {
-		return
+	//The other fields are filled even when the config has no level pattern
+	if level := cfgFile.matchers.lineLevel(last_matches); level != "" {
+		issue_map["LogLevel"] = level
 	}
-	match = log_rgx.FindStringSubmatch(last_matches)
-	if len(match) > 1 {
-		issue_map["LogLevel"] = match[1]
+	issueContent, _ := log.view(issue_lines)
+	for field, field_rgx := range cfgFile.matchers.other_fields {
+		setFieldContent(field_rgx, issueContent, field, issue_map)
 	}
-	for field, field_rgx := range cfgFile.IssuesGeneralFields.OtherFields {
-		field_issue := issues_map[issue_name]
-		setFieldContent(field_rgx, issueContent, field, field_issue)
+
+	for field, field_rgx := range matchers.additional_fields {
+		setFieldContent(field_rgx, issueContent, field, issue_map)
+		result.header_fields = append(result.header_fields, field)
 	}
+	return result
+}
 
-	for field, field_rgx := range issue.additional_fields {
-		field_issue := issues_map[issue_name]
-		setFieldContent(field_rgx, issueContent, field, field_issue)
-		headerMap[field] = true
+// groupKey encodes captured values without ambiguity, each value is prefixed with its length
+func groupKey(values []string) string {
+	var key strings.Builder
+	for _, value := range values {
+		key.WriteString(strconv.Itoa(len(value)))
+		key.WriteByte(':')
+		key.WriteString(value)
 	}
+	return key.String()
 }
-func fillGroupDetails(group_content map[string][][]string, group_count map[string][]int, issueContent string, group_rgx *regexp.Regexp) (string, int) {
-	last_matches := ""
-	issueContentSlice := strings.Split(issueContent, "\n")
-	for _, log := range issueContentSlice {
-		matches := group_rgx.FindStringSubmatch(log)
-		if len(matches) > 2 {
-			last_matches = log
+func fillGroupDetails(group_details GroupedStruct, log *logLines, group_lines []int, group_rgx *regexp.Regexp) ([]int, int) {
+	group_content, group_count, group_refs := group_details.Group_content, group_details.Group_count, group_details.Group_refs
+	//Index of every row by its group and values, so finding the row of a match does not scan the group
+	row_index := make(map[string]int)
+	matched_lines := []int{}
+	for _, index := range group_lines {
+		log_line := log.lines[index]
+		loc := group_rgx.FindStringSubmatchIndex(log_line)
+		if len(loc) > 4 {
+			matched_lines = append(matched_lines, index)
+			matches := make([]string, len(loc)/2)
+			for i := range matches {
+				if loc[2*i] >= 0 {
+					matches[i] = log_line[loc[2*i]:loc[2*i+1]]
+				}
+			}
+			ref := log.ref(lineMatch{index: index, offset: log.offsets[index] + loc[0]})
 			if group_content[matches[1]] == nil {
 				group_content[matches[1]] = [][]string{}
 				group_count[matches[1]] = []int{}
+				group_refs[matches[1]] = [][]LineRef{}
 			}
-			exist := false
-			for index, grp_detail := range group_content[matches[1]] {
-				if reflect.DeepEqual(grp_detail, matches[2:]) {
-					group_count[matches[1]][index] += 1
-					exist = true
-					break
-				}
-			}
-			if !exist {
+			key := groupKey(matches[1:])
+			if grp_index, exist := row_index[key]; exist {
+				group_count[matches[1]][grp_index] += 1
+				group_refs[matches[1]][grp_index] = append(group_refs[matches[1]][grp_index], ref)
+			} else {
+				row_index[key] = len(group_content[matches[1]])
 				group_count[matches[1]] = append(group_count[matches[1]], 1)
 				group_content[matches[1]] = append(group_content[matches[1]], matches[2:])
+				group_refs[matches[1]] = append(group_refs[matches[1]], []LineRef{ref})
 			}
 		}
 	}
@@ -261,57 +440,52 @@ func fillGroupDetails(group_content map[string][][]string, group_count map[strin
 			issues_count += num
 		}
 	}
-	return last_matches, issues_count
+	return matched_lines, issues_count
 }
-func nongroupIssueDetails(issue Issue, cfgFile *Config, headerMap map[string]bool, issueContent string, issue_name string, issues_map map[string]map[string]string, ngroup_map map[string]map[string]bool) {
-	issue_rgx, err := regexp.Compile(issue.regex)
-	if err != nil {
-		return
+func nongroupIssueDetails(issue Issue, cfgFile *Config, log *logLines, issue_lines []int, log_scan *literalScan, issue_name string) issueResult {
+	result := issueResult{issue_name: issue_name, details: make(map[string]string), matches: []LineRef{}}
+	matchers := cfgFile.matchers.issues[issue_name]
+	if matchers.regex.isEmpty() {
+		return result
 	}
-	filter_logs := issue_rgx.FindAllString(issueContent, -1)
-	filter_logs_map := make(map[string]bool)
-	for _, filter_log := range filter_logs {
-		filter_logs_map[filter_log] = true
+	line_matches := matchers.regex.matchLines(log, issue_lines, log_scan)
+	for _, line_match := range line_matches {
+		result.matches = append(result.matches, log.ref(line_match))
 	}
-	ngroup_map[issue_name] = filter_logs_map
-	issue_map := issues_map[issue_name]
+	result.claimed_logs = matchedLines(line_matches)
+	filter_logs := matchTexts(line_matches)
+	issue_map := result.details
 	issue_map["Number"] = strconv.Itoa(len(filter_logs))
-	issueContent = strings.Join(filter_logs, "\n")
+	issueContent := strings.Join(filter_logs, "\n")
 	if len(filter_logs) > 0 {
-		log_rgx, err := regexp.Compile(cfgFile.IssuesGeneralFields.Log_level)
-		if err != nil {
-			return
+		if level := cfgFile.matchers.lineLevel(filter_logs[0]); level != "" {
+			issue_map["LogLevel"] = level
 		}
-		match := log_rgx.FindStringSubmatch(filter_logs[0])
-		if len(match) > 1 {
-			issue_map["LogLevel"] = match[1]
+		for field, field_rgx := range cfgFile.matchers.other_fields {
+			setFieldContent(field_rgx, issueContent, field, issue_map)
 		}
-		for field, field_rgx := range cfgFile.IssuesGeneralFields.OtherFields {
-			field_issue := issues_map[issue_name]
-			setFieldContent(field_rgx, issueContent, field, field_issue)
+		for field, field_rgx := range matchers.additional_fields {
+			setFieldContent(field_rgx, issueContent, field, issue_map)
+			result.header_fields = append(result.header_fields, field)
 		}
-		for field, field_rgx := range issue.additional_fields {
-			field_issue := issues_map[issue_name]
-			setFieldContent(field_rgx, issueContent, field, field_issue)
-			headerMap[field] = true
+		timestampRegex := cfgFile.matchers.timestamp
+		if timestampRegex == nil {
+			return result
 		}
-		timestampRegex, _ := regexp.Compile(cfgFile.IssuesGeneralFields.Timestamp)
-		match = timestampRegex.FindStringSubmatch(filter_logs[len(filter_logs)-1])
+		result.times = matchTimes(timestampRegex, filter_logs)
+		match := timestampRegex.FindStringSubmatch(filter_logs[len(filter_logs)-1])
 		if len(match) > 0 {
 			issue_map["Timestamp"] = match[0]
 		}
 	}
+	return result
 }
-func getFieldContent(field_rgx string, issueContent string) string {
-	field_rgx_comp, err := regexp.Compile(field_rgx)
-	if err != nil {
-		return ""
-	}
-	match := field_rgx_comp.FindAllString(issueContent, -1)
+func getFieldContent(field_rgx *regexp.Regexp, issueContent string) string {
+	match := field_rgx.FindAllString(issueContent, -1)
 	fieldContent := strconv.Itoa(len(match)) + " :  " + strings.Join(match, "\n")
 	return fieldContent
 }
-func setFieldContent(field_rgx string, issueContent string, field string, field_issue map[string]string) {
+func setFieldContent(field_rgx *regexp.Regexp, issueContent string, field string, field_issue map[string]string) {
 	field_content := getFieldContent(field_rgx, issueContent)
 	field_issue[field] = field_content
 }
@@ -0,0 +1,206 @@
+package report
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testConfig reads a yaml config the way the buckets' configs are read
+func testConfig(t testing.TB, data string) *Config {
+	cfg := &Config{}
+	if err := readConfig([]byte(data), cfg); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// scanLog is a synthetic log where line i is logged by Tag<i%8> and Conn<i%8>
+func scanLog(lines int) *logLines {
+	var content strings.Builder
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&content, "10-19 14:%02d:%02d.000  1000  %d E Tag%d: message %d, Conn%d: ssid=net%d reason=%d\n", (i/60)%60, i%60, 2000+i%13, i%8, i, i%8, i%3, i%5)
+	}
+	return splitLines("scan.txt", content.String())
+}
+
+// scanConfig has a regex issue and a group issue for every tag of scanLog
+func scanConfig(t testing.TB) *Config {
+	cfg := &Config{Issues: make(map[string]Issue)}
+	whole_log := map[string]Matcher{"whole_log": {Regex: `(?m)^.*$`}}
+	for i := 0; i < 8; i++ {
+		cfg.Issues[fmt.Sprintf("Tag%d", i)] = Issue{regex: fmt.Sprintf(`Tag%d: message \d+`, i), specific_process: whole_log}
+		cfg.Issues[fmt.Sprintf("Conn%d", i)] = Issue{grouping: fmt.Sprintf(`(Conn%d): ssid=(\S+) reason=(\d+)`, i), detailing_mode: "group", specific_process: whole_log}
+	}
+	matchers, err := compileConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.matchers = matchers
+	return cfg
+}
+
+// The issues scanned in parallel get the same details as the issues scanned one by one
+func TestGetIssueDetailsParallel(t *testing.T) {
+	cfg := scanConfig(t)
+	log := scanLog(800)
+	log_scan := cfg.matchers.scan(log)
+	issues_map := make(map[string]map[string]string)
+	grp_issues := make(map[string]GroupedStruct)
+	ngrp_issues := make(map[string][]LineRef)
+	claimed_logs := make(map[int]bool)
+	getIssueDetails(cfg, log, log_scan, make(map[string]bool), issues_map, make(map[string][]int), grp_issues, ngrp_issues, make(map[string][]time.Time), claimed_logs)
+	for issue_name, issue := range cfg.Issues {
+		if issue.detailing_mode == "group" {
+			expected := groupIssueDetails(issue, cfg, log, nil, log_scan, issue_name)
+			if !reflect.DeepEqual(grp_issues[issue_name], expected.group_details) || !reflect.DeepEqual(issues_map[issue_name], expected.details) {
+				t.Errorf("%s: got %v, expected %v", issue_name, issues_map[issue_name], expected.details)
+			}
+			continue
+		}
+		expected := nongroupIssueDetails(issue, cfg, log, nil, log_scan, issue_name)
+		if len(ngrp_issues[issue_name]) != 100 || !reflect.DeepEqual(ngrp_issues[issue_name], expected.matches) || !reflect.DeepEqual(issues_map[issue_name], expected.details) {
+			t.Errorf("%s: got %d matches, expected 100", issue_name, len(ngrp_issues[issue_name]))
+		}
+	}
+	if len(claimed_logs) != 800 {
+		t.Errorf("%d lines claimed, expected 800", len(claimed_logs))
+	}
+}
+
+// Issues only look in the lines of their specific processes
+func TestIssueSpecificProcessScope(t *testing.T) {
+	cfg := testConfig(t, `
+Issues:
+  Unscoped:
+    regex: '.*FATAL.*'
+  Wifi:
+    regex: '.*FATAL.*'
+    specific_process:
+      wifi: '(?m)^.*WifiService.*$'
+  Whole:
+    regex: '.*FATAL.*'
+    specific_process:
+      whole_log: '(?m)^.*$'
+`)
+	log := splitLines("scope.txt", "10-19 14:00:01.000  1000  1001 E WifiService: FATAL scan\n10-19 14:00:02.000  1234  1250 E AndroidRuntime: FATAL EXCEPTION: main")
+	issues_map := make(map[string]map[string]string)
+	getIssueDetails(cfg, log, cfg.matchers.scan(log), make(map[string]bool), issues_map, make(map[string][]int), make(map[string]GroupedStruct), make(map[string][]LineRef), make(map[string][]time.Time), make(map[int]bool))
+	for issue_name, number := range map[string]string{"Unscoped": "0", "Wifi": "1", "Whole": "2"} {
+		if issues_map[issue_name]["Number"] != number {
+			t.Errorf("%s: %s matches, expected %s", issue_name, issues_map[issue_name]["Number"], number)
+		}
+	}
+}
+
+// Issues get their timestamp, times and fields when the config has no LogLevel pattern
+func TestIssueDetailsWithoutLogLevel(t *testing.T) {
+	log := splitLines("wifi.txt", "10-19 14:00:01.000  1000  1001 W WifiService: connect failed ssid=home\n10-19 14:00:05.000  1000  1001 W WifiService: connect failed ssid=work")
+	issues := `
+IssuesGeneralFields:
+  Timestamp: '\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{3}'
+  OtherFields:
+    Ssid: 'ssid=\w+'
+Issues:
+  Connect:
+    regex: '.*connect failed.*'
+    fields:
+      Network: 'ssid=\w+'
+  Grouped:
+    detailing_mode: group
+    grouping: '(connect) failed ssid=(\w+)'
+    fields:
+      Network: 'ssid=\w+'
+`
+	for name, levels := range map[string]string{
+		"no level":          "",
+		"LogLevels.Pattern": "LogLevels:\n  Pattern: '^\\S+ \\S+\\s+\\d+\\s+\\d+ ([A-Z]) '\n",
+	} {
+		cfg := testConfig(t, levels+issues)
+		for issue_name, issue := range cfg.Issues {
+			var result issueResult
+			if issue.detailing_mode == "group" {
+				result = groupIssueDetails(issue, cfg, log, nil, cfg.matchers.scan(log), issue_name)
+			} else {
+				result = nongroupIssueDetails(issue, cfg, log, nil, cfg.matchers.scan(log), issue_name)
+			}
+			details := result.details
+			if details["Number"] != "2" || details["Timestamp"] != "10-19 14:00:05.000" || details["Ssid"] == "" || details["Network"] == "" || len(result.times) != 2 {
+				t.Errorf("%s, %s: details %v, %d times", name, issue_name, details, len(result.times))
+			}
+			if level := details["LogLevel"]; (levels == "") != (level == "") {
+				t.Errorf("%s, %s: level %q", name, issue_name, level)
+			}
+		}
+	}
+}
+
+// BenchmarkGetIssueDetails compares the parallel scan of the issues with scanning them one by one
+func BenchmarkGetIssueDetails(b *testing.B) {
+	cfg := scanConfig(b)
+	log := scanLog(200000)
+	log_scan := cfg.matchers.scan(log)
+	proc_lines := issueProcessLogs(cfg, log, log_scan, make(map[string][]int))
+	b.Run("parallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			getIssueDetails(cfg, log, log_scan, make(map[string]bool), make(map[string]map[string]string), proc_lines, make(map[string]GroupedStruct), make(map[string][]LineRef), make(map[string][]time.Time), make(map[int]bool))
+		}
+	})
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for issue_name, issue := range cfg.Issues {
+				issue_lines := issueLines(proc_lines, issue.specific_process)
+				if issue.detailing_mode == "group" {
+					groupIssueDetails(issue, cfg, log, issue_lines, log_scan, issue_name)
+				} else {
+					nongroupIssueDetails(issue, cfg, log, issue_lines, log_scan, issue_name)
+				}
+			}
+		}
+	})
+}
+
+// Values that read the same once concatenated are different rows
+func TestFillGroupDetailsKeys(t *testing.T) {
+	values := [][]string{{"ab", "c"}, {"a", "bc"}, {"abc", ""}, {"", "abc"}, {"1:a", "b"}, {"1", "a1:b"}, {"ab", "c"}}
+	lines := []string{}
+	for _, value := range values {
+		lines = append(lines, "connect first="+value[0]+"|second="+value[1]+"|")
+	}
+	log := splitLines("keys.txt", strings.Join(lines, "\n"))
+	group_details := GroupedStruct{Group_content: make(map[string][][]string), Group_count: make(map[string][]int), Group_refs: make(map[string][][]LineRef)}
+	group_lines := []int{0, 1, 2, 3, 4, 5, 6}
+	fillGroupDetails(group_details, log, group_lines, regexp.MustCompile(`(connect) first=([^|]*)\|second=([^|]*)\|`))
+	if !reflect.DeepEqual(group_details.Group_content["connect"], values[:6]) || !reflect.DeepEqual(group_details.Group_count["connect"], []int{2, 1, 1, 1, 1, 1}) {
+		t.Errorf("rows %q, counts %v", group_details.Group_content["connect"], group_details.Group_count["connect"])
+	}
+	for _, keys := range [][2][]string{{{"ab", "c"}, {"a", "bc"}}, {{"1:a", "b"}, {"1", "a1:b"}}, {{"", "x"}, {"x", ""}}} {
+		if groupKey(keys[0]) == groupKey(keys[1]) {
+			t.Errorf("%q and %q share the key %q", keys[0], keys[1], groupKey(keys[0]))
+		}
+	}
+}
+
+// BenchmarkFillGroupDetails groups matches that are all distinct rows, the time per match stays the same as they grow
+func BenchmarkFillGroupDetails(b *testing.B) {
+	group_rgx := regexp.MustCompile(`(Tag\d): message (\d+)`)
+	for _, matches := range []int{25000, 100000, 200000} {
+		log := scanLog(matches)
+		group_lines := make([]int, matches)
+		for index := range group_lines {
+			group_lines[index] = index
+		}
+		b.Run(fmt.Sprintf("%dk", matches/1000), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				group_details := GroupedStruct{Group_content: make(map[string][][]string), Group_count: make(map[string][]int), Group_refs: make(map[string][][]LineRef)}
+				if _, count := fillGroupDetails(group_details, log, group_lines, group_rgx); count != matches {
+					b.Fatalf("%d matches grouped, expected %d", count, matches)
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*matches), "ns/match")
+		})
+	}
+}
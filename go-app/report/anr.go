@@ -0,0 +1,221 @@
+package report
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	maxAnrMainFrames   = 20
+	maxAnrThreadFrames = 8
+	//Lines after "ANR in" where ActivityManager prints the reason
+	maxAnrReasonLines = 10
+)
+
+var (
+	anrStart   = regexp.MustCompile(`----- pid (\d+) at (.*?) -----`)
+	anrEnd     = regexp.MustCompile(`----- end \d+ -----`)
+	anrCmdLine = regexp.MustCompile(`Cmd line: (.*)$`)
+	anrThread  = regexp.MustCompile(`"([^"]*)"(?: daemon)?\s+prio=\d+(?:\s+tid=(\d+))?\s+(.*?)\s*$`)
+	anrNative  = regexp.MustCompile(`native: #\d+ pc \S+\s+(.*?)\s*$`)
+	anrMonitor = regexp.MustCompile(`- (waiting to lock|waiting on|locked|sleeping on|parking to wait for) <(0x[0-9a-f]+)> \(a ([\w$.]+)\)(?: held by thread (\d+))?`)
+	anrIn      = regexp.MustCompile(`\bANR in (\S+)`)
+	anrReason  = regexp.MustCompile(`\bReason: (.*?)\s*$`)
+)
+
+// AnrSummary is one "----- pid -----" block of ANR traces: the main thread, the contended locks and the
+// threads waiting on a monitor. Reason comes from the "ANR in" lines of ActivityManager when the log has them
+type AnrSummary struct {
+	Pid     string
+	Process string
+	Time    string
+	Reason  string
+	Line    int
+	End     int
+	Threads int
+	Main    AnrThread
+	Locks   []AnrLock
+	Waiting []AnrThread
+}
+type AnrThread struct {
+	Name    string
+	Tid     string
+	State   string
+	Frames  []string
+	Waiting string
+	Lock    string
+	HeldBy  string
+	Locked  []string
+}
+
+// AnrLock is a lock that other threads wait for, Holder is the thread that holds it
+type AnrLock struct {
+	Address string
+	Class   string
+	Holder  string
+	Waiters []string
+}
+
+// Blocked is set when the main thread does not run, the usual cause of an ANR
+func (thread AnrThread) Blocked() bool {
+	return thread.State != "" && thread.State != "Runnable" && thread.State != "Native"
+}
+func (thread AnrThread) Label() string {
+	if thread.Tid == "" {
+		return thread.Name
+	}
+	return thread.Name + " (tid " + thread.Tid + ")"
+}
+
+// parseAnrs reads the ANR trace blocks of a bugreport or a traces.txt
+func parseAnrs(log *logLines) []AnrSummary {
+	anrs := []AnrSummary{}
+	reasons := anrReasons(log)
+	for index := 0; index < len(log.lines); index++ {
+		start := anrStart.FindStringSubmatch(log.lines[index])
+		if start == nil {
+			continue
+		}
+		anr := AnrSummary{Pid: start[1], Time: start[2], Line: index + 1}
+		threads := []*AnrThread{}
+		var thread *AnrThread
+		for index++; index < len(log.lines) && !anrEnd.MatchString(log.lines[index]) && !anrStart.MatchString(log.lines[index]); index++ {
+			line := log.lines[index]
+			if cmd := anrCmdLine.FindStringSubmatch(line); cmd != nil && anr.Process == "" {
+				anr.Process = strings.TrimSpace(cmd[1])
+			} else if header := anrThread.FindStringSubmatch(line); header != nil {
+				thread = &AnrThread{Name: header[1], Tid: header[2], State: strings.Fields(header[3] + " ")[0]}
+				if strings.HasPrefix(header[3], "(not attached)") {
+					thread.State = "Native"
+				}
+				threads = append(threads, thread)
+			} else if thread == nil {
+				continue
+			} else if frame := traceFrame.FindStringSubmatch(line); frame != nil {
+				thread.Frames = append(thread.Frames, frame[1]+"("+frame[2]+")")
+			} else if frame := anrNative.FindStringSubmatch(line); frame != nil {
+				thread.Frames = append(thread.Frames, frame[1])
+			} else if monitor := anrMonitor.FindStringSubmatch(line); monitor != nil {
+				lock := "<" + monitor[2] + "> (a " + monitor[3] + ")"
+				if monitor[1] == "locked" {
+					thread.Locked = append(thread.Locked, lock)
+				} else if thread.Waiting == "" {
+					thread.Waiting = monitor[1] + " " + lock
+					thread.Lock = monitor[2]
+					thread.HeldBy = monitor[4]
+				}
+			}
+		}
+		anr.End = index
+		if index < len(log.lines) && anrEnd.MatchString(log.lines[index]) {
+			anr.End = index + 1
+		} else {
+			//The next dump starts on this line, it is read by the next iteration
+			index--
+		}
+		anr.Threads = len(threads)
+		anr.Reason = reasons[anr.Process]
+		summarizeAnr(&anr, threads)
+		anrs = append(anrs, anr)
+	}
+	return anrs
+}
+
+// summarizeAnr keeps the main thread, the threads waiting on a monitor and the locks they wait for
+func summarizeAnr(anr *AnrSummary, threads []*AnrThread) {
+	by_tid := make(map[string]*AnrThread)
+	holders := make(map[string]*AnrThread)
+	for _, thread := range threads {
+		if thread.Tid != "" {
+			by_tid[thread.Tid] = thread
+		}
+		for _, lock := range thread.Locked {
+			holders[lockAddress(lock)] = thread
+		}
+	}
+	locks := make(map[string]*AnrLock)
+	for _, thread := range threads {
+		if thread.HeldBy != "" {
+			if holder, ok := by_tid[thread.HeldBy]; ok {
+				thread.HeldBy = holder.Label()
+			} else {
+				thread.HeldBy = "tid " + thread.HeldBy
+			}
+		} else if holder, ok := holders[thread.Lock]; ok && thread.Lock != "" && holder != thread {
+			thread.HeldBy = holder.Label()
+		}
+		if thread.Lock != "" && thread.HeldBy != "" {
+			lock, ok := locks[thread.Lock]
+			if !ok {
+				lock = &AnrLock{Address: thread.Lock, Class: lockClass(thread.Waiting), Holder: thread.HeldBy}
+				locks[thread.Lock] = lock
+			}
+			lock.Waiters = append(lock.Waiters, thread.Label())
+		}
+	}
+	for _, thread := range threads {
+		is_main := thread.Name == "main" && anr.Main.Name == ""
+		if is_main {
+			anr.Main = *thread
+		}
+		max_frames := maxAnrThreadFrames
+		if is_main {
+			max_frames = maxAnrMainFrames
+		}
+		frames := thread.Frames
+		if len(frames) > max_frames {
+			frames = frames[:max_frames]
+		}
+		if is_main {
+			anr.Main.Frames = frames
+		} else if thread.Waiting != "" && thread.HeldBy != "" {
+			waiting := *thread
+			waiting.Frames = frames
+			anr.Waiting = append(anr.Waiting, waiting)
+		}
+	}
+	for _, lock := range locks {
+		anr.Locks = append(anr.Locks, *lock)
+	}
+	sort.Slice(anr.Locks, func(i, j int) bool {
+		if len(anr.Locks[i].Waiters) != len(anr.Locks[j].Waiters) {
+			return len(anr.Locks[i].Waiters) > len(anr.Locks[j].Waiters)
+		}
+		return anr.Locks[i].Address < anr.Locks[j].Address
+	})
+}
+
+// lockAddress and lockClass read "<0x0a1b2c3d> (a java.lang.Object)", optionally after "waiting to lock "
+func lockAddress(lock string) string {
+	start, end := strings.Index(lock, "<"), strings.Index(lock, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return lock[start+1 : end]
+}
+func lockClass(lock string) string {
+	start := strings.Index(lock, "(a ")
+	if start < 0 {
+		return ""
+	}
+	return strings.TrimSuffix(lock[start+len("(a "):], ")")
+}
+
+// anrReasons maps the processes of the "ANR in" lines to the reason printed below them
+func anrReasons(log *logLines) map[string]string {
+	reasons := make(map[string]string)
+	for index, line := range log.lines {
+		anr := anrIn.FindStringSubmatch(line)
+		if anr == nil {
+			continue
+		}
+		for next := index + 1; next < len(log.lines) && next <= index+maxAnrReasonLines; next++ {
+			if reason := anrReason.FindStringSubmatch(log.lines[next]); reason != nil {
+				reasons[anr[1]] = reason[1]
+				break
+			}
+		}
+	}
+	return reasons
+}
@@ -0,0 +1,102 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+)
+
+// A dump that directly follows another one, without its "----- end" line, is read too
+func TestParseBackToBackAnrs(t *testing.T) {
+	log := splitLines("traces.txt", `----- pid 1234 at 2026-10-19 10:00:00 -----
+Cmd line: com.example.app
+"main" prio=5 tid=1 Blocked
+  at com.example.app.Cache.get(Cache.java:10)
+  - waiting to lock <0x0a1b2c3d> (a java.lang.Object) held by thread 12
+"Thread-5" prio=5 tid=12 Runnable
+  at com.example.app.Worker.run(Worker.java:30)
+  - locked <0x0a1b2c3d> (a java.lang.Object)
+----- pid 5678 at 2026-10-19 10:00:05 -----
+Cmd line: com.example.other
+"main" prio=5 tid=1 Native
+  native: #00 pc 000a2f6c  /system/lib64/libc.so (__epoll_pwait+8)
+----- end 5678 -----
+----- pid 9012 at 2026-10-19 10:00:09 -----
+Cmd line: system_server
+"main" prio=5 tid=1 Waiting
+  at java.lang.Object.wait(Native method)`)
+	anrs := parseAnrs(log)
+	if len(anrs) != 3 {
+		t.Fatalf("expected 3 dumps, got %+v", anrs)
+	}
+	expected := []struct {
+		pid     string
+		process string
+		line    int
+		end     int
+		blocked bool
+	}{
+		{"1234", "com.example.app", 1, 8, true},
+		{"5678", "com.example.other", 9, 13, false},
+		{"9012", "system_server", 14, 17, true},
+	}
+	for index, anr := range anrs {
+		want := expected[index]
+		if anr.Pid != want.pid || anr.Process != want.process || anr.Line != want.line || anr.End != want.end || anr.Main.Blocked() != want.blocked {
+			t.Errorf("dump %d: %+v", index, anr)
+		}
+	}
+	if main := anrs[0].Main; main.HeldBy != "Thread-5 (tid 12)" || len(anrs[0].Locks) != 1 {
+		t.Errorf("first dump main thread %+v, locks %+v", main, anrs[0].Locks)
+	}
+}
+
+// The reason comes from the "ANR in" lines of ActivityManager, the locks from the monitors of the threads
+func TestParseAnrFromBugreport(t *testing.T) {
+	log := splitLines("bugreport.txt", `10-19 10:00:00.120  1021  1055 E ActivityManager: ANR in com.example.app (com.example.app/.MainActivity)
+10-19 10:00:00.120  1021  1055 E ActivityManager: PID: 1234
+10-19 10:00:00.120  1021  1055 E ActivityManager: Reason: Input dispatching timed out (Waiting to send non-key event because the touched window has not finished processing)
+10-19 10:00:00.120  1021  1055 E ActivityManager: Load: 4.2 / 3.9 / 3.1
+
+----- pid 1234 at 2026-10-19 10:00:00 -----
+Cmd line: com.example.app
+
+"main" prio=5 tid=1 Blocked
+  | group="main" sCount=1 dsCount=0 flags=1 obj=0x72c3a1f8 self=0x7b3c2a1e40
+  at com.example.app.data.Cache.get(Cache.java:52)
+  - waiting to lock <0x0a1b2c3d> (a com.example.app.data.Cache) held by thread 14
+  at com.example.app.MainActivity.onResume(MainActivity.java:88)
+  at android.app.Activity.performResume(Activity.java:8135)
+
+"Binder:1234_2" prio=5 tid=9 Blocked
+  at com.example.app.data.Cache.put(Cache.java:70)
+  - waiting to lock <0x0a1b2c3d> (a com.example.app.data.Cache) held by thread 14
+
+"DiskWorker" prio=5 tid=14 Native
+  native: #00 pc 00000000000d0b8c  /apex/com.android.runtime/lib64/bionic/libc.so (fsync+12)
+  at java.io.FileDescriptor.sync(Native method)
+  at com.example.app.data.Cache.flush(Cache.java:101)
+  - locked <0x0a1b2c3d> (a com.example.app.data.Cache)
+
+"HeapTaskDaemon" daemon prio=5 tid=7 WaitingForTaskProcessor
+  at dalvik.system.VMRuntime.runHeapTasks(Native method)
+
+----- end 1234 -----`)
+	anrs := parseAnrs(log)
+	if len(anrs) != 1 {
+		t.Fatalf("%+v", anrs)
+	}
+	anr := anrs[0]
+	if anr.Process != "com.example.app" || anr.Reason != "Input dispatching timed out (Waiting to send non-key event because the touched window has not finished processing)" || anr.Threads != 4 || anr.Line != 6 || anr.End != 29 {
+		t.Errorf("dump %+v", anr)
+	}
+	if !anr.Main.Blocked() || anr.Main.HeldBy != "DiskWorker (tid 14)" || len(anr.Main.Frames) != 3 || anr.Main.Frames[0] != "com.example.app.data.Cache.get(Cache.java:52)" {
+		t.Errorf("main thread %+v", anr.Main)
+	}
+	expected := []AnrLock{{Address: "0x0a1b2c3d", Class: "com.example.app.data.Cache", Holder: "DiskWorker (tid 14)", Waiters: []string{"main (tid 1)", "Binder:1234_2 (tid 9)"}}}
+	if !reflect.DeepEqual(anr.Locks, expected) {
+		t.Errorf("locks %+v", anr.Locks)
+	}
+	if len(anr.Waiting) != 1 || anr.Waiting[0].Name != "Binder:1234_2" {
+		t.Errorf("waiting threads %+v", anr.Waiting)
+	}
+}
@@ -0,0 +1,227 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Comparison struct {
+	Base   AnalysisDetails
+	Target AnalysisDetails
+	Issues []IssueDelta
+	Groups []GroupDelta
+	Events []EventDelta
+}
+type IssueDelta struct {
+	Issue  string
+	Base   int
+	Target int
+	Delta  int
+	Status string
+}
+type GroupDelta struct {
+	Issue  string
+	Group  string
+	Values []string
+	Base   int
+	Target int
+	Delta  int
+}
+type EventDelta struct {
+	Event    string
+	Template string
+	Base     int
+	Target   int
+}
+
+func CompareLogs(w http.ResponseWriter, r *http.Request, project_id string, region_id string, store *AnalysisStore) (Comparison, error) {
+	cfgName, bucket, err := selectedConfig(r, project_id, region_id)
+	if err != nil {
+		return Comparison{}, err
+	}
+	cfgFile := &Config{}
+	if err := extractConfig(cfgName, bucket, cfgFile); err != nil {
+		return Comparison{}, err
+	}
+	base, err := comparedAnalysis(r, "Base", cfgFile, cfgName, bucket, store)
+	if err != nil {
+		return Comparison{}, err
+	}
+	target, err := comparedAnalysis(r, "Target", cfgFile, cfgName, bucket, store)
+	if err != nil {
+		return Comparison{}, err
+	}
+	return compareAnalyses(cfgFile, base, target), nil
+}
+func comparedAnalysis(r *http.Request, side string, cfgFile *Config, cfgName string, bucket string, store *AnalysisStore) (*FullDetails, error) {
+	if id := r.FormValue(side + "Analysis"); id != "" {
+		fullLogDetails, ok := store.Get(id)
+		if !ok {
+			return nil, errors.New("The selected analysis is no longer available")
+		}
+		details := fullLogDetails.Analysis_details
+		if details.ConfigName != cfgName || details.Platform != bucket {
+			return nil, fmt.Errorf("%s was analysed with %s/%s, not %s/%s", details.FileName, details.Platform, details.ConfigName, bucket, cfgName)
+		}
+		return fullLogDetails, nil
+	}
+	fContent, fName, err := readLogFile(r, side+"File")
+	if err != nil {
+		return nil, err
+	}
+	fullLogDetails := &FullDetails{}
+	analyseContent(fullLogDetails, cfgFile, fContent, *fName, cfgName, bucket)
+	store.Add(*fullLogDetails)
+	return fullLogDetails, nil
+}
+func compareAnalyses(cfgFile *Config, base *FullDetails, target *FullDetails) Comparison {
+	comparison := Comparison{
+		Base:   base.Analysis_details,
+		Target: target.Analysis_details,
+	}
+	comparison.Issues = compareIssues(base.Analysis_details.Issues, target.Analysis_details.Issues)
+	comparison.Groups = compareGroups(base.GroupedIssues, target.GroupedIssues)
+	comparison.Events = compareEvents(cfgFile, base.Analysis_details.RawLog, target.Analysis_details.RawLog)
+	return comparison
+}
+func compareIssues(base map[string]map[string]string, target map[string]map[string]string) []IssueDelta {
+	names := make(map[string]bool)
+	for issue := range base {
+		names[issue] = true
+	}
+	for issue := range target {
+		names[issue] = true
+	}
+	deltas := make([]IssueDelta, 0, len(names))
+	for issue := range names {
+		base_count, _ := strconv.Atoi(base[issue]["Number"])
+		target_count, _ := strconv.Atoi(target[issue]["Number"])
+		delta := IssueDelta{Issue: issue, Base: base_count, Target: target_count, Delta: target_count - base_count}
+		switch {
+		case base_count == 0 && target_count > 0:
+			delta.Status = "New"
+		case base_count > 0 && target_count == 0:
+			delta.Status = "Gone"
+		case delta.Delta != 0:
+			delta.Status = "Changed"
+		default:
+			delta.Status = "Unchanged"
+		}
+		deltas = append(deltas, delta)
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		if abs(deltas[i].Delta) != abs(deltas[j].Delta) {
+			return abs(deltas[i].Delta) > abs(deltas[j].Delta)
+		}
+		return deltas[i].Issue < deltas[j].Issue
+	})
+	return deltas
+}
+func compareGroups(base map[string]GroupedStruct, target map[string]GroupedStruct) []GroupDelta {
+	deltas := []GroupDelta{}
+	issues := make(map[string]bool)
+	for issue := range base {
+		issues[issue] = true
+	}
+	for issue := range target {
+		issues[issue] = true
+	}
+	for issue := range issues {
+		base_rows := groupRows(base[issue])
+		target_rows := groupRows(target[issue])
+		for key, row := range target_rows {
+			if base_row, ok := base_rows[key]; !ok || base_row.Base != row.Base {
+				deltas = append(deltas, GroupDelta{Issue: issue, Group: row.Group, Values: row.Values, Base: base_row.Base, Target: row.Base, Delta: row.Base - base_row.Base})
+			}
+		}
+		for key, row := range base_rows {
+			if _, ok := target_rows[key]; !ok {
+				deltas = append(deltas, GroupDelta{Issue: issue, Group: row.Group, Values: row.Values, Base: row.Base, Delta: -row.Base})
+			}
+		}
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		if deltas[i].Issue != deltas[j].Issue {
+			return deltas[i].Issue < deltas[j].Issue
+		}
+		if abs(deltas[i].Delta) != abs(deltas[j].Delta) {
+			return abs(deltas[i].Delta) > abs(deltas[j].Delta)
+		}
+		return deltas[i].Group < deltas[j].Group
+	})
+	return deltas
+}
+
+// groupRows indexes every group row by its group and captured values, the count is kept in Base
+func groupRows(grouped GroupedStruct) map[string]GroupDelta {
+	rows := make(map[string]GroupDelta)
+	for group, contents := range grouped.Group_content {
+		for index, values := range contents {
+			named_values := make([]string, len(values))
+			for i, value := range values {
+				named_values[i] = value
+				if i+2 < len(grouped.Group_names) && grouped.Group_names[i+2] != "" {
+					named_values[i] = grouped.Group_names[i+2] + "=" + value
+				}
+			}
+			key := group + "\x00" + strings.Join(values, "\x00")
+			rows[key] = GroupDelta{Group: group, Values: named_values, Base: grouped.Group_count[group][index]}
+		}
+	}
+	return rows
+}
+func compareEvents(cfgFile *Config, base_log string, target_log string) []EventDelta {
+	log_rgx := cfgFile.matchers.log_level
+	base_events := eventTemplates(cfgFile, log_rgx, base_log)
+	target_events := eventTemplates(cfgFile, log_rgx, target_log)
+	deltas := []EventDelta{}
+	for key, count := range base_events {
+		if _, ok := target_events[key]; !ok {
+			deltas = append(deltas, EventDelta{Event: key[0], Template: key[1], Base: count})
+		}
+	}
+	for key, count := range target_events {
+		if _, ok := base_events[key]; !ok {
+			deltas = append(deltas, EventDelta{Event: key[0], Template: key[1], Target: count})
+		}
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		if deltas[i].Event != deltas[j].Event {
+			return deltas[i].Event < deltas[j].Event
+		}
+		return deltas[i].Template < deltas[j].Template
+	})
+	return deltas
+}
+
+// eventTemplates counts the important events of a log by event name and masked message
+func eventTemplates(cfgFile *Config, log_rgx *regexp.Regexp, fContent string) map[[2]string]int {
+	events := make(map[int][]EventHit)
+	getImportantEvents(cfgFile, fContent, events)
+	contentSlice := strings.Split(fContent, "\n")
+	templates := make(map[[2]string]int)
+	for line, hits := range events {
+		message := contentSlice[line]
+		if log_rgx != nil {
+			if loc := log_rgx.FindStringIndex(message); loc != nil {
+				message = message[loc[1]:]
+			}
+		}
+		message = maskVariableTokens(strings.TrimSpace(message))
+		for _, hit := range hits {
+			templates[[2]string{hit.Name, message}]++
+		}
+	}
+	return templates
+}
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
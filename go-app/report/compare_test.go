@@ -0,0 +1,32 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Issue deltas are sorted by the size of the change, group rows are told apart by their values
+func TestCompareIssuesAndGroups(t *testing.T) {
+	base := map[string]map[string]string{"Anr": {"Number": "5"}, "Crash": {"Number": "2"}, "Wifi": {"Number": "3"}}
+	target := map[string]map[string]string{"Anr": {"Number": "5"}, "Wifi": {"Number": "7"}, "Watchdog": {"Number": "1"}}
+	expected := []IssueDelta{
+		{Issue: "Wifi", Base: 3, Target: 7, Delta: 4, Status: "Changed"},
+		{Issue: "Crash", Base: 2, Target: 0, Delta: -2, Status: "Gone"},
+		{Issue: "Watchdog", Base: 0, Target: 1, Delta: 1, Status: "New"},
+		{Issue: "Anr", Base: 5, Target: 5, Delta: 0, Status: "Unchanged"},
+	}
+	if deltas := compareIssues(base, target); !reflect.DeepEqual(deltas, expected) {
+		t.Errorf("issues %+v", deltas)
+	}
+	names := []string{"", "", "ssid", "reason"}
+	base_groups := map[string]GroupedStruct{"Connect": {Group_names: names, Group_content: map[string][][]string{"connect": {{"home", "1"}, {"work", "2"}}}, Group_count: map[string][]int{"connect": {3, 1}}}}
+	target_groups := map[string]GroupedStruct{"Connect": {Group_names: names, Group_content: map[string][][]string{"connect": {{"home", "1"}, {"cafe", "3"}}}, Group_count: map[string][]int{"connect": {6, 2}}}}
+	expected_groups := []GroupDelta{
+		{Issue: "Connect", Group: "connect", Values: []string{"ssid=home", "reason=1"}, Base: 3, Target: 6, Delta: 3},
+		{Issue: "Connect", Group: "connect", Values: []string{"ssid=cafe", "reason=3"}, Base: 0, Target: 2, Delta: 2},
+		{Issue: "Connect", Group: "connect", Values: []string{"ssid=work", "reason=2"}, Base: 1, Target: 0, Delta: -1},
+	}
+	if deltas := compareGroups(base_groups, target_groups); !reflect.DeepEqual(deltas, expected_groups) {
+		t.Errorf("groups %+v", deltas)
+	}
+}
@@ -0,0 +1,111 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// issueContext is the context shown around every occurrence of an issue, in lines and/or as a time window
+type issueContext struct {
+	before int
+	after  int
+	window time.Duration
+}
+
+// Occurrence is one or more matches of an issue shown with their context, Start and End are line indexes
+type Occurrence struct {
+	Start int
+	End   int
+	Lines []OccurrenceLine
+}
+type OccurrenceLine struct {
+	Number  int
+	Content string
+	Matches int
+	Process string
+	Notes   []Note
+}
+
+// Before and After are the indexes the expand controls start from
+func (occurrence Occurrence) Before() int {
+	return occurrence.Start - 1
+}
+func (occurrence Occurrence) After() int {
+	return occurrence.End + 1
+}
+func compileContext(issue Issue) (issueContext, error) {
+	context := issueContext{before: issue.context_before, after: issue.context_after}
+	if issue.context_before < 0 || issue.context_after < 0 {
+		return context, errors.New("context_before and context_after must be positive")
+	}
+	if issue.context_window != "" {
+		window, err := time.ParseDuration(issue.context_window)
+		if err != nil || window <= 0 {
+			return context, fmt.Errorf("invalid context_window %q", issue.context_window)
+		}
+		context.window = window
+	}
+	return context, nil
+}
+
+// issueOccurrences returns the matches of an issue with their context, occurrences whose contexts touch are merged
+func issueOccurrences(log *logLines, matches []LineRef, context issueContext, timestamp_rgx *regexp.Regexp) []Occurrence {
+	occurrences := []Occurrence{}
+	for _, match := range matches {
+		index := match.Line - 1
+		if index < 0 || index >= len(log.lines) {
+			continue
+		}
+		start, end := contextRange(log, index, context, timestamp_rgx)
+		if len(occurrences) == 0 || start > occurrences[len(occurrences)-1].End+1 {
+			occurrences = append(occurrences, Occurrence{Start: start, End: start - 1})
+		}
+		occurrence := &occurrences[len(occurrences)-1]
+		for line := occurrence.End + 1; line <= end; line++ {
+			occurrence.Lines = append(occurrence.Lines, OccurrenceLine{Number: line + 1, Content: log.lines[line]})
+		}
+		if end > occurrence.End {
+			occurrence.End = end
+		}
+		occurrence.Lines[index-occurrence.Start].Matches++
+	}
+	return occurrences
+}
+
+// contextRange returns the first and last lines shown around a match. With a time window, the lines
+// without timestamp next to the match are kept as they usually continue the previous line
+func contextRange(log *logLines, index int, context issueContext, timestamp_rgx *regexp.Regexp) (int, int) {
+	start, end := index-context.before, index+context.after
+	if start < 0 {
+		start = 0
+	}
+	if end > len(log.lines)-1 {
+		end = len(log.lines) - 1
+	}
+	if context.window == 0 || timestamp_rgx == nil {
+		return start, end
+	}
+	match_time, ok := parseTimestamp(timestamp_rgx.FindString(log.lines[index]))
+	if !ok {
+		return start, end
+	}
+	for line := index - 1; line >= 0; line-- {
+		if t, ok := parseTimestamp(timestamp_rgx.FindString(log.lines[line])); ok && match_time.Sub(t) > context.window {
+			break
+		}
+		if line < start {
+			start = line
+		}
+	}
+	for line := index + 1; line < len(log.lines); line++ {
+		if t, ok := parseTimestamp(timestamp_rgx.FindString(log.lines[line])); ok && t.Sub(match_time) > context.window {
+			break
+		}
+		if line > end {
+			end = line
+		}
+	}
+	return start, end
+}
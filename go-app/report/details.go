@@ -1,6 +1,7 @@
 package report
 
 import (
+	"encoding/json"
 	"html/template"
 	"net/http"
 	"regexp"
@@ -10,30 +11,33 @@ import (
 	"sync"
 )
 
-var (
-	Log_levels = map[string][]string{"Ios": []string{"Critical", "Error", "Warning", "Notice", "Info", "Debug", "Trace"},
-		"my-android-bucket": []string{"Assert", "Error", "Warning", "Info", "Debug", "Verbose"}}
-	log_levels_map = map[string]map[string]string{"Ios": map[string]string{"Critical": "C", "Error": "E", "Warning": "W", "Notice": "N", "Info": "I", "Debug": "D", "Trace": "T"},
-		"my-android-bucket": map[string]string{"Assert": "A", "Error": "E", "Warning": "W", "Info": "I", "Debug": "D", "Verbose": "V"}}
-	log_levels_rgx = map[string]map[string]string{"Ios": map[string]string{"start": "", "end": ""},
-		"my-android-bucket": map[string]string{"start": "(?m)^(?:0[1-9]|1[0-2])-(?:0[1-9]|(?:1|2)[0-9]|3(?:0|1))\\s(?:(?:(?:0|1)[0-9])|(?:2[0-3])):[0-5][0-9]:[0-5][0-9]\\.\\d{3}(?:\\s)*\\d{4,5}(?:\\s)*\\d{4,5}\\s", "end": "\\s.*"}}
-)
+const lineContextSize = 20
 
 func LogReport(w http.ResponseWriter, r *http.Request, fullLogDetails *FullDetails, cfgFile *Config) {
 	file := r.URL.Path[len("/report/"):]
 	switch file {
-	case fullLogDetails.Analysis_details.FileName:
+	case fullLogDetails.Analysis_details.FileName, "raw":
 		loadRawLog(w, r, fullLogDetails)
 	case "events":
 		loadEvents(w, r, fullLogDetails, cfgFile)
+	case "search":
+		loadSearch(w, r, fullLogDetails, cfgFile)
+	case "lines":
+		loadLineRange(w, r, fullLogDetails, cfgFile)
 	default:
-		if file[:7] == "Details" {
+		if strings.HasPrefix(file, "Line/") {
+			loadLineContext(w, file[len("Line/"):], fullLogDetails, cfgFile)
+		} else if strings.HasPrefix(file, "Pivot/") {
+			loadPivot(w, r, file[len("Pivot/"):], fullLogDetails, cfgFile)
+		} else if strings.HasPrefix(file, "Details") {
 			issue_name := r.URL.Path[len("/report/Details/"):]
 			_, ok := fullLogDetails.GroupedIssues[issue_name]
-			if ok {
-				loadGroupDetails(w, issue_name, fullLogDetails)
+			if ok && r.FormValue("group") != "" {
+				loadGroupLines(w, r, issue_name, fullLogDetails, cfgFile)
+			} else if ok {
+				loadGroupDetails(w, r, issue_name, fullLogDetails)
 			} else {
-				loadNonGroupDetails(w, issue_name, fullLogDetails)
+				loadNonGroupDetails(w, issue_name, fullLogDetails, cfgFile)
 			}
 		} else {
 			loadSpecificLogs(w, file, fullLogDetails)
@@ -46,65 +50,273 @@ func loadSpecificLogs(w http.ResponseWriter, file string, fullLogDetails *FullDe
 		"detailType": func() string { return "SpecificLog" },
 		"countLine":  CountLine,
 	}
-	detail_template, err := template.New("details.html").Funcs(FuncMap).ParseFiles("templates/details.html")
+	detail_template, err := template.New("details.html").Funcs(FuncMap).ParseFiles("templates/details.html", "templates/histogram.html")
 	template := template.Must(detail_template, err)
 	template.Execute(w, fullLogDetails.Analysis_details.SpecificProcess[file])
 }
-func loadGroupDetails(w http.ResponseWriter, issue_name string, fullLogDetails *FullDetails) {
+
+// loadGroupDetails serves /report/Details/{issue}?sort=1&desc=true&top=50&f0=filter, the grouped table of an issue
+func loadGroupDetails(w http.ResponseWriter, r *http.Request, issue_name string, fullLogDetails *FullDetails) {
+	grouped := fullLogDetails.GroupedIssues[issue_name]
+	sort_column := -1
+	if value := r.FormValue("sort"); value != "" {
+		sort_column, _ = strconv.Atoi(value)
+	}
+	top := defaultGroupTop
+	if value := r.FormValue("top"); value != "" {
+		top, _ = strconv.Atoi(value)
+	}
+	filters := make([]string, len(grouped.Group_names)+1)
+	for column := range filters {
+		filters[column] = r.FormValue("f" + strconv.Itoa(column))
+	}
 	FuncMap := template.FuncMap{
 		"detailType": func() string { return "Group" },
 		"countLine":  CountLine,
 	}
-	detail_template, err := template.New("details.html").Funcs(FuncMap).ParseFiles("templates/details.html")
+	detail_template, err := template.New("details.html").Funcs(FuncMap).ParseFiles("templates/details.html", "templates/histogram.html")
 	template := template.Must(detail_template, err)
-	template.Execute(w, fullLogDetails.GroupedIssues[issue_name])
+	table := buildGroupTable(issue_name, grouped, sort_column, r.FormValue("desc") == "true", filters, top)
+	for index, row := range table.Rows {
+		table.Rows[index].Known = fullLogDetails.KnownRows[rowKey(issue_name, row.Group, row.Values)]
+	}
+	template.Execute(w, struct {
+		Table      GroupTable
+		Histogram  Histogram
+		IssueNotes []Note
+	}{
+		table,
+		fullLogDetails.Analysis_details.Histograms[issue_name],
+		fullLogDetails.Analysis_details.Notes.ForIssue(issue_name),
+	})
 }
-func loadNonGroupDetails(w http.ResponseWriter, issue_name string, fullLogDetails *FullDetails) {
-	issue_num, _ := strconv.Atoi(fullLogDetails.Analysis_details.Issues[issue_name]["Number"])
-	hightlight := make(map[int]bool) //index=> true = must be highlight
-	details := nonGroupDetails(fullLogDetails.Analysis_details.RawLog, fullLogDetails.NonGroupedIssues[issue_name], issue_num, hightlight)
+
+// loadGroupLines serves /report/Details/{issue}?group=name&row=0, the lines of one row of a grouped table
+func loadGroupLines(w http.ResponseWriter, r *http.Request, issue_name string, fullLogDetails *FullDetails, cfgFile *Config) {
+	group_refs := fullLogDetails.GroupedIssues[issue_name].Group_refs[r.FormValue("group")]
+	row, err := strconv.Atoi(r.FormValue("row"))
+	if err != nil || row < 0 || row >= len(group_refs) {
+		http.Error(w, "Invalid row "+r.FormValue("row")+" of "+r.FormValue("group"), http.StatusBadRequest)
+		return
+	}
+	loadOccurrences(w, issue_name, group_refs[row], Histogram{}, fullLogDetails, cfgFile)
+}
+func loadNonGroupDetails(w http.ResponseWriter, issue_name string, fullLogDetails *FullDetails, cfgFile *Config) {
+	loadOccurrences(w, issue_name, fullLogDetails.NonGroupedIssues[issue_name], fullLogDetails.Analysis_details.Histograms[issue_name], fullLogDetails, cfgFile)
+}
+
+// loadOccurrences shows the matched lines of an issue with their context
+func loadOccurrences(w http.ResponseWriter, issue_name string, refs []LineRef, histogram Histogram, fullLogDetails *FullDetails, cfgFile *Config) {
+	log := splitLines(fullLogDetails.Analysis_details.FileName, fullLogDetails.Analysis_details.RawLog)
+	context := issueContext{}
+	var timestamp_rgx *regexp.Regexp
+	if cfgFile.matchers != nil {
+		context = cfgFile.matchers.issues[issue_name].context
+		timestamp_rgx = cfgFile.matchers.timestamp
+	}
+	occurrences := issueOccurrences(log, refs, context, timestamp_rgx)
+	if cfgFile.matchers != nil {
+		labelProcesses(occurrences, fullLogDetails.Analysis_details.Timeline, cfgFile.matchers.pid)
+	}
+	for _, occurrence := range occurrences {
+		attachNotes(occurrence.Lines, fullLogDetails.Analysis_details.Notes)
+	}
 	FuncMap := template.FuncMap{
 		"detailType": func() string { return "nonGroup" },
 		"countLine":  CountLine,
 	}
-	detail_template, err := template.New("details.html").Funcs(FuncMap).ParseFiles("templates/details.html")
+	detail_template, err := template.New("details.html").Funcs(FuncMap).ParseFiles("templates/details.html", "templates/histogram.html")
 	template := template.Must(detail_template, err)
 	template.Execute(w, struct {
-		Highlight map[int]bool
-		Details   []string
+		Id          string
+		Issue       string
+		Occurrences []Occurrence
+		LogSize     int
+		Histogram   Histogram
+		Pid         bool
+		Tid         bool
+		IssueNotes  []Note
 	}{
-		hightlight, details,
+		fullLogDetails.Analysis_details.Id, issue_name, occurrences, len(log.lines), histogram,
+		cfgFile.matchers != nil && cfgFile.matchers.pid != nil,
+		cfgFile.matchers != nil && cfgFile.matchers.tid != nil,
+		fullLogDetails.Analysis_details.Notes.ForIssue(issue_name),
 	})
 }
+
+// loadSearch serves /report/search?q=query&page=0&size=100 as JSON
+func loadSearch(w http.ResponseWriter, r *http.Request, fullLogDetails *FullDetails, cfgFile *Config) {
+	page, _ := strconv.Atoi(r.FormValue("page"))
+	page_size, _ := strconv.Atoi(r.FormValue("size"))
+	log := splitLines(fullLogDetails.Analysis_details.FileName, fullLogDetails.Analysis_details.RawLog)
+	results, err := searchLog(log, cfgFile.matchers, r.FormValue("q"), page, page_size)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	jsonValue, _ := json.Marshal(results)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonValue)
+}
+
+// loadLineRange serves /report/lines?start=0&count=200&level=Error&process=name&pid=1234 as JSON
+func loadLineRange(w http.ResponseWriter, r *http.Request, fullLogDetails *FullDetails, cfgFile *Config) {
+	start, _ := strconv.Atoi(r.FormValue("start"))
+	count, _ := strconv.Atoi(r.FormValue("count"))
+	filter := lineFilter{level: r.FormValue("level"), process: r.FormValue("process"), pid: r.FormValue("pid")}
+	if filter.level == "All" {
+		filter.level = ""
+	}
+	line_range, err := lineRange(fullLogDetails, cfgFile.matchers, filter, start, count)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	jsonValue, _ := json.Marshal(line_range)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonValue)
+}
+
+// loadLineContext serves /report/Line/{line}, the line with the lines around it
+func loadLineContext(w http.ResponseWriter, line_path string, fullLogDetails *FullDetails, cfgFile *Config) {
+	line, err := strconv.Atoi(line_path)
+	log := splitLines(fullLogDetails.Analysis_details.FileName, fullLogDetails.Analysis_details.RawLog)
+	if err != nil || line < 1 || line > len(log.lines) {
+		http.Error(w, "Invalid line "+line_path, http.StatusBadRequest)
+		return
+	}
+	occurrences := issueOccurrences(log, []LineRef{{File: log.file, Line: line}}, issueContext{before: lineContextSize, after: lineContextSize}, nil)
+	for _, occurrence := range occurrences {
+		attachNotes(occurrence.Lines, fullLogDetails.Analysis_details.Notes)
+	}
+	FuncMap := template.FuncMap{
+		"detailType": func() string { return "nonGroup" },
+		"countLine":  CountLine,
+	}
+	detail_template, err := template.New("details.html").Funcs(FuncMap).ParseFiles("templates/details.html", "templates/histogram.html")
+	template := template.Must(detail_template, err)
+	template.Execute(w, struct {
+		Id          string
+		Issue       string
+		Occurrences []Occurrence
+		LogSize     int
+		Histogram   Histogram
+		Pid         bool
+		Tid         bool
+		IssueNotes  []Note
+	}{
+		fullLogDetails.Analysis_details.Id, "", occurrences, len(log.lines), Histogram{},
+		cfgFile.matchers != nil && cfgFile.matchers.pid != nil,
+		cfgFile.matchers != nil && cfgFile.matchers.tid != nil,
+		nil,
+	})
+}
+
+// loadPivot serves /report/Pivot/{pid|tid}/{line}?issue=name
+func loadPivot(w http.ResponseWriter, r *http.Request, pivot_path string, fullLogDetails *FullDetails, cfgFile *Config) {
+	parts := strings.SplitN(pivot_path, "/", 2)
+	if len(parts) != 2 {
+		http.Error(w, "Expected /report/Pivot/{pid|tid}/{line}", http.StatusBadRequest)
+		return
+	}
+	line, err := strconv.Atoi(parts[1])
+	if err != nil {
+		http.Error(w, "Invalid line "+parts[1], http.StatusBadRequest)
+		return
+	}
+	issue_name := r.URL.Query().Get("issue")
+	log := splitLines(fullLogDetails.Analysis_details.FileName, fullLogDetails.Analysis_details.RawLog)
+	pivot, err := buildPivot(log, cfgFile.matchers, parts[0], line-1, issueRefs(fullLogDetails, issue_name))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pivot.Issue = issue_name
+	attachNotes(pivot.Lines, fullLogDetails.Analysis_details.Notes)
+	if pivot.Field == "pid" {
+		pivot.Process = fullLogDetails.Analysis_details.Timeline.ProcessAt(pivot.Value, pivot.Line)
+	}
+	FuncMap := template.FuncMap{
+		"detailType": func() string { return "Pivot" },
+		"countLine":  CountLine,
+	}
+	detail_template, err := template.New("details.html").Funcs(FuncMap).ParseFiles("templates/details.html", "templates/histogram.html")
+	template := template.Must(detail_template, err)
+	template.Execute(w, struct {
+		Pivot
+		Id string
+	}{pivot, fullLogDetails.Analysis_details.Id})
+}
 func loadEvents(w http.ResponseWriter, r *http.Request, fullLogDetails *FullDetails, cfgFile *Config) {
-	fullLogDetails.ImportantEvents = make(map[int]string)
-	logs_size := getImportantEvents(cfgFile, fullLogDetails.Analysis_details.RawLog, fullLogDetails.ImportantEvents)
-	ev_lines := make([]int, 0, len(fullLogDetails.ImportantEvents))
-	for line, _ := range fullLogDetails.ImportantEvents {
+	filter := eventFilter{fullLogDetails.Analysis_details.Id, r.FormValue("view"), r.FormValue("category"), r.FormValue("severity"), eventCategories(cfgFile.matchers), eventSeverities}
+	events := filterEvents(fullLogDetails.ImportantEvents, filter.Category, filter.Severity)
+	if filter.View == "timeline" {
+		loadTimeAxis(w, fullLogDetails, cfgFile, events, filter)
+		return
+	}
+	ev_lines := make([]int, 0, len(events))
+	for line, _ := range events {
 		ev_lines = append(ev_lines, line)
 	}
 	sort.Ints(ev_lines)
-	event_logs := make([]string, 0, len(fullLogDetails.ImportantEvents))
+	event_logs := make([]string, 0, len(events))
+	event_notes := make(map[int][]Note)
+	shown_notes := make(map[int]bool)
 	contentSlice := strings.Split(fullLogDetails.Analysis_details.RawLog, "\n")
+	logs_size := len(contentSlice)
 	for _, line := range ev_lines {
 		event_logs = append(event_logs, contentSlice[line])
+		for _, note := range fullLogDetails.Analysis_details.Notes.ForLine(line + 1) {
+			if !shown_notes[note.Id] {
+				shown_notes[note.Id] = true
+				event_notes[line] = append(event_notes[line], note)
+			}
+		}
 	}
 	event_template, err := template.New("events.html").Funcs(template.FuncMap{"add": func(x, y int) int {
 		return x + y
 	}, "substract": func(x, y int) int {
 		return x - y
-	}}).ParseFiles("templates/events.html")
+	}}).ParseFiles("templates/events.html", "templates/eventfilter.html")
 	template := template.Must(event_template, err)
 	template.Execute(w, struct {
+		Id         string
 		MatchLines []int
-		Events     map[int]string
+		Events     map[int][]EventHit
 		LogSize    int
 		EventLogs  []string
+		Notes      map[int][]Note
+		Filter     eventFilter
 	}{
+		fullLogDetails.Analysis_details.Id,
 		ev_lines,
-		fullLogDetails.ImportantEvents,
+		events,
 		logs_size,
 		event_logs,
+		event_notes,
+		filter,
+	})
+}
+
+// loadTimeAxis serves /report/events?view=timeline, the events, issues and processes on a time axis
+func loadTimeAxis(w http.ResponseWriter, fullLogDetails *FullDetails, cfgFile *Config, events map[int][]EventHit, filter eventFilter) {
+	axis, err := buildTimeAxis(fullLogDetails, cfgFile.matchers, events)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	axis_template, err := template.New("timeaxis.html").Funcs(template.FuncMap{"percent": func(value float64) string {
+		return strconv.FormatFloat(value, 'f', 3, 64) + "%"
+	}}).ParseFiles("templates/timeaxis.html", "templates/eventfilter.html")
+	template := template.Must(axis_template, err)
+	template.Execute(w, struct {
+		TimeAxis
+		Id     string
+		Filter eventFilter
+	}{
+		axis,
+		fullLogDetails.Analysis_details.Id,
+		filter,
 	})
 }
 func loadRawLog(w http.ResponseWriter, r *http.Request, fullLogDetails *FullDetails) {
@@ -112,83 +324,58 @@ func loadRawLog(w http.ResponseWriter, r *http.Request, fullLogDetails *FullDeta
 		"detailType": func() string { return "RawLog" },
 		"countLine":  CountLine,
 	}
-	detail_template, err := template.New("details.html").Funcs(FuncMap).ParseFiles("templates/details.html")
+	detail_template, err := template.New("details.html").Funcs(FuncMap).ParseFiles("templates/details.html", "templates/histogram.html")
 	template := template.Must(detail_template, err)
+	processes := make([]string, 0, len(fullLogDetails.Analysis_details.SpecificProcess))
+	for process := range fullLogDetails.Analysis_details.SpecificProcess {
+		processes = append(processes, process)
+	}
+	sort.Strings(processes)
 	template.Execute(w, struct {
-		Rawlog    string
+		Id        string
 		LogLevels []string
+		Processes []string
+		Notes     []Note
 	}{
-		fullLogDetails.Analysis_details.RawLog,
-		Log_levels[fullLogDetails.Analysis_details.Platform],
+		fullLogDetails.Analysis_details.Id,
+		fullLogDetails.Analysis_details.LogLevels,
+		processes,
+		fullLogDetails.Analysis_details.Notes.All(),
 	})
 }
 func CountLine(content string) int {
 	return len(strings.Split(content, "\n"))
 }
-func nonGroupDetails(fContent string, nonGroupedIssues map[string]bool, num_issue int, hightlight map[int]bool) []string {
-	details := make([]string, 0, num_issue*2+1)
-	last_found_index := 0
-	contentLines := strings.Split(fContent, "\n")
-	found_issue := 0
-	for index, line := range contentLines {
-		if nonGroupedIssues[line] {
-			if last_found_index != index {
-				details = append(details, strings.Join(contentLines[last_found_index:index], "\n"))
-				hightlight[len(details)-1] = false
-			}
-			details = append(details, line)
-			last_found_index = index + 1
-			hightlight[len(details)-1] = true
-			found_issue += 1
-
-			if found_issue == num_issue {
-				details = append(details, strings.Join(contentLines[last_found_index:], "\n"))
-				hightlight[len(details)-1] = false
-				break
-			}
-		}
 
-	}
-	return details
-}
-func getImportantEvents(cfgFile *Config, fContent string, importantEvents map[int]string) int {
-	if len(cfgFile.ImportantEvents) < 1 {
+func getImportantEvents(cfgFile *Config, fContent string, importantEvents map[int][]EventHit) int {
+	if cfgFile.matchers == nil || len(cfgFile.matchers.important_events) < 1 {
 		return 0
 	}
-	contentMap := make(map[string]int)
-	contentSlice := strings.Split(fContent, "\n")
-	for index, line := range contentSlice {
-		contentMap[line] = index
-	}
+	log := splitLines("", fContent)
 	var waitGroup sync.WaitGroup
 	var mutex sync.Mutex
-	waitGroup.Add(len(cfgFile.ImportantEvents))
-	for ev, ev_rgx := range cfgFile.ImportantEvents {
-		go func(ev string, ev_rgx string) {
-			ev_rgx_comp, err := regexp.Compile(ev_rgx)
-			if err != nil {
-				waitGroup.Done()
-				return
+	waitGroup.Add(len(cfgFile.matchers.important_events))
+	log_scan := cfgFile.matchers.scan(log)
+	for ev, ev_matcher := range cfgFile.matchers.important_events {
+		go func(ev string, ev_matcher eventMatcher) {
+			hits := make(map[int]EventHit)
+			for _, match := range ev_matcher.matchLines(log, nil, log_scan) {
+				//An event is shown once per line, with the fields of its first match
+				if _, ok := hits[match.index]; !ok {
+					hits[match.index] = ev_matcher.eventHit(ev, match)
+				}
 			}
-			ev_content := ev_rgx_comp.FindAllString(fContent, -1)
-			if len(ev_content) > 0 {
+			if len(hits) > 0 {
 				mutex.Lock()
-				for _, match_ev := range ev_content {
-					importantEvents[contentMap[match_ev]] = ev
+				for line, hit := range hits {
+					importantEvents[line] = append(importantEvents[line], hit)
 				}
 				mutex.Unlock()
 			}
 			waitGroup.Done()
-		}(ev, ev_rgx)
+		}(ev, ev_matcher)
 	}
 	waitGroup.Wait()
-	return len(contentSlice)
-}
-func GetLogLeveldetails(platform string, level string, fContent string) string {
-	level_rgx := log_levels_rgx[platform]["start"] + log_levels_map[platform][level] + log_levels_rgx[platform]["end"]
-	lev_rgx_comp, err := regexp.Compile(level_rgx)
-	if err != nil {
-		return ""
-	}
-	return strings.Join(lev_rgx_comp.FindAllString(fContent, -1), "\n")
+	sortEventHits(importantEvents)
+	return len(log.lines)
 }
@@ -0,0 +1,155 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+)
+
+// eventSeverities from the least to the most severe, events are "info" unless the config says otherwise
+var eventSeverities = []string{"debug", "info", "warning", "error", "critical"}
+
+// ImportantEvent is an event of the config: a pattern, and the category and severity it is shown with
+type ImportantEvent struct {
+	Matcher
+	Category string
+	Severity string
+}
+
+// EventHit is one event found on a line, Fields are the named groups of its regex such as the SSID of a connect event
+type EventHit struct {
+	Name     string
+	Category string
+	Severity string
+	Fields   []EventField
+}
+type EventField struct {
+	Name  string
+	Value string
+}
+
+// eventFilter is the category and minimum severity picked on the events pages, and the choices.
+// Id is the analysis the filter links stay on
+type eventFilter struct {
+	Id         string
+	View       string
+	Category   string
+	Severity   string
+	Categories []string
+	Severities []string
+}
+type eventMatcher struct {
+	lineMatcher
+	category string
+	severity string
+}
+
+// extractEvent reads an event that is a pattern, or a map of pattern entries with category and severity
+func extractEvent(value interface{}) ImportantEvent {
+	event := ImportantEvent{Matcher: extractMatcher(value)}
+	if entries, ok := value.(map[interface{}]interface{}); ok {
+		event.Category, _ = entries["category"].(string)
+		event.Severity, _ = entries["severity"].(string)
+	}
+	return event
+}
+func extractEvents(values map[string]interface{}) map[string]ImportantEvent {
+	events := make(map[string]ImportantEvent)
+	for name, value := range values {
+		events[name] = extractEvent(value)
+	}
+	return events
+}
+
+// String shows an event with its fields, such as "connect ssid=home"
+func (hit EventHit) String() string {
+	text := hit.Name
+	for _, field := range hit.Fields {
+		text += " " + field.Name + "=" + field.Value
+	}
+	return text
+}
+func (hit EventHit) trackName() string {
+	if hit.Category == "" {
+		return hit.Name
+	}
+	return hit.Category + ": " + hit.Name
+}
+func severityRank(severity string) int {
+	for rank, name := range eventSeverities {
+		if name == severity {
+			return rank
+		}
+	}
+	return -1
+}
+func checkSeverity(severity string) (string, error) {
+	if severity == "" {
+		return "info", nil
+	}
+	if severityRank(severity) < 0 {
+		return "", fmt.Errorf("unknown severity %q, expected one of %v", severity, eventSeverities)
+	}
+	return severity, nil
+}
+
+// eventHit returns the event of one match with the named groups of the regex
+func (matcher eventMatcher) eventHit(name string, match lineMatch) EventHit {
+	hit := EventHit{Name: name, Category: matcher.category, Severity: matcher.severity}
+	if matcher.regex == nil {
+		return hit
+	}
+	groups := matcher.regex.SubexpNames()
+	values := matcher.regex.FindStringSubmatch(match.text)
+	for index := 1; index < len(values) && index < len(groups); index++ {
+		if groups[index] != "" && values[index] != "" {
+			hit.Fields = append(hit.Fields, EventField{groups[index], values[index]})
+		}
+	}
+	return hit
+}
+
+// sortEventHits orders the events of every line from the most severe, then by name
+func sortEventHits(events map[int][]EventHit) {
+	for _, hits := range events {
+		sort.Slice(hits, func(i, j int) bool {
+			if hits[i].Severity != hits[j].Severity {
+				return severityRank(hits[i].Severity) > severityRank(hits[j].Severity)
+			}
+			return hits[i].Name < hits[j].Name
+		})
+	}
+}
+
+// filterEvents keeps the events of a category and of a minimum severity, an empty filter keeps everything
+func filterEvents(events map[int][]EventHit, category string, severity string) map[int][]EventHit {
+	if category == "" && severity == "" {
+		return events
+	}
+	min_rank := severityRank(severity)
+	filtered := make(map[int][]EventHit)
+	for line, hits := range events {
+		for _, hit := range hits {
+			if (category == "" || hit.Category == category) && severityRank(hit.Severity) >= min_rank {
+				filtered[line] = append(filtered[line], hit)
+			}
+		}
+	}
+	return filtered
+}
+
+// eventCategories lists the categories of the config for the category filter
+func eventCategories(matchers *matcherSet) []string {
+	categories := []string{}
+	if matchers == nil {
+		return categories
+	}
+	found := make(map[string]bool)
+	for _, event := range matchers.important_events {
+		if event.category != "" && !found[event.category] {
+			found[event.category] = true
+			categories = append(categories, event.category)
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
@@ -0,0 +1,63 @@
+package report
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const eventsConfig = `
+ImportantEvents:
+  Boot: 'boot completed'
+  Connect:
+    regex: 'connected to (?P<ssid>\w+) on (?P<band>\w+)'
+    category: wifi
+  Disconnect:
+    regex: 'disconnected reason=(?P<reason>\d+)'
+    category: wifi
+    severity: warning
+  Crash:
+    contains: FATAL EXCEPTION
+    category: app
+    severity: critical
+  Restart:
+    regex: 'wifi restart'
+    category: wifi
+    severity: error
+`
+
+// Events carry their named groups, category and severity, and are filtered by category and minimum severity
+func TestImportantEventHits(t *testing.T) {
+	cfg := testConfig(t, eventsConfig)
+	events := make(map[int][]EventHit)
+	getImportantEvents(cfg, strings.Join([]string{
+		"I SystemServer: boot completed",
+		"I Wifi: connected to home on 5GHz",
+		"W Wifi: disconnected reason=3",
+		"E AndroidRuntime: FATAL EXCEPTION: main, wifi restart",
+	}, "\n"), events)
+	if len(events) != 4 || events[0][0].String() != "Boot" || events[0][0].Severity != "info" || events[0][0].trackName() != "Boot" {
+		t.Fatalf("events %+v", events)
+	}
+	connect := events[1][0]
+	if connect.String() != "Connect ssid=home band=5GHz" || connect.Severity != "info" || connect.trackName() != "wifi: Connect" {
+		t.Errorf("connect %+v", connect)
+	}
+	if names := []string{events[3][0].Name, events[3][1].Name}; !reflect.DeepEqual(names, []string{"Crash", "Restart"}) {
+		t.Errorf("the events of a line are not sorted by severity: %v", names)
+	}
+	filtered := filterEvents(events, "wifi", "warning")
+	if len(filtered) != 2 || filtered[2][0].Name != "Disconnect" || len(filtered[3]) != 1 || filtered[3][0].Name != "Restart" {
+		t.Errorf("wifi events from warning %+v", filtered)
+	}
+	if filtered := filterEvents(events, "", "critical"); len(filtered) != 1 || filtered[3][0].Name != "Crash" {
+		t.Errorf("critical events %+v", filtered)
+	}
+	if categories := eventCategories(cfg.matchers); !reflect.DeepEqual(categories, []string{"app", "wifi"}) {
+		t.Errorf("categories %v", categories)
+	}
+	err := readConfig([]byte("ImportantEvents:\n  Boot:\n    regex: 'boot completed'\n    severity: urgent\n"), &Config{})
+	if err == nil || !strings.Contains(err.Error(), `ImportantEvents.Boot: unknown severity "urgent"`) {
+		t.Errorf("unknown severity: %v", err)
+	}
+}
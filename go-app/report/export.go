@@ -0,0 +1,342 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxExportedLines = 5
+
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// ExportedReport is the JSON export: the analysis without its raw log, and the line of every match
+type ExportedReport struct {
+	Analysis         AnalysisDetails
+	GroupedIssues    map[string]GroupedStruct
+	NonGroupedIssues map[string][]LineRef
+}
+
+// ExportReport writes an analysis as a json, csv, md or html download. For csv, table selects
+// the issues table (default) or the grouped tables ("groups")
+func ExportReport(w http.ResponseWriter, fullLogDetails *FullDetails, format string, table string) error {
+	name := strings.TrimSuffix(fullLogDetails.Analysis_details.FileName, filepath.Ext(fullLogDetails.Analysis_details.FileName)) + ".report"
+	var content []byte
+	var err error
+	switch format {
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		exported := ExportedReport{fullLogDetails.Analysis_details, fullLogDetails.GroupedIssues, fullLogDetails.NonGroupedIssues}
+		exported.Analysis.RawLog = ""
+		content, err = json.MarshalIndent(exported, "", "  ")
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		if table == "groups" {
+			name += ".groups"
+			content, err = exportGroupsCsv(fullLogDetails)
+		} else {
+			content, err = exportIssuesCsv(fullLogDetails)
+		}
+	case "md":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		content = exportMarkdown(fullLogDetails)
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		content, err = exportHtml(fullLogDetails)
+	default:
+		return fmt.Errorf("Unknown export format %q, expected json, csv, md or html", format)
+	}
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + "." + format}))
+	w.Write(content)
+	return nil
+}
+
+// issueCells returns the issues table like report.html shows it: same header order and N/A for the empty fields.
+// The Details column lists the first matched lines and Occurrences is formatted by occurrences
+func issueCells(fullLogDetails *FullDetails, occurrences func(Histogram) string) [][]string {
+	details := fullLogDetails.Analysis_details
+	rows := make([][]string, 0, len(details.OrderedIssues))
+	for _, issue := range details.OrderedIssues {
+		row := make([]string, 0, len(details.Header))
+		for _, field := range details.Header {
+			cell := ""
+			switch field {
+			case "Issue":
+				cell = issue
+			case "Details":
+				cell = linesSummary(issueRefs(fullLogDetails, issue))
+			case "Occurrences":
+				if histogram := details.Histograms[issue]; len(histogram.Counts) > 0 {
+					cell = occurrences(histogram)
+				}
+			default:
+				cell = details.Issues[issue][field]
+			}
+			if cell == "" {
+				cell = "N/A"
+			}
+			row = append(row, cell)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// linesSummary lists the first distinct matched lines, such as "L12, L40 and 3 more"
+func linesSummary(refs []LineRef) string {
+	lines := []string{}
+	for index, ref := range refs {
+		if index > 0 && refs[index-1].Line == ref.Line {
+			continue
+		}
+		lines = append(lines, "L"+strconv.Itoa(ref.Line))
+	}
+	if len(lines) > maxExportedLines {
+		return strings.Join(lines[:maxExportedLines], ", ") + fmt.Sprintf(" and %d more", len(lines)-maxExportedLines)
+	}
+	return strings.Join(lines, ", ")
+}
+func hasIssueNotes(notes *Notes) bool {
+	for _, note := range notes.All() {
+		if note.Issue != "" {
+			return true
+		}
+	}
+	return false
+}
+func issueNoteText(notes *Notes, issue string) string {
+	texts := []string{}
+	for _, note := range notes.ForIssue(issue) {
+		texts = append(texts, note.Text)
+	}
+	return strings.Join(texts, "\n")
+}
+func histogramCounts(histogram Histogram) string {
+	counts := make([]string, len(histogram.Counts))
+	for index, count := range histogram.Counts {
+		counts[index] = strconv.Itoa(count)
+	}
+	return strings.Join(counts, " ")
+}
+func histogramSparkline(histogram Histogram) string {
+	spark := make([]rune, len(histogram.Counts))
+	for index := range histogram.Counts {
+		spark[index] = sparkBars[histogram.Height(index)*(len(sparkBars)-1)/100]
+	}
+	return string(spark)
+}
+
+// groupedRows returns the rows of the grouped tables sorted by issue, group and count
+func groupedRows(fullLogDetails *FullDetails) []GroupDelta {
+	rows := []GroupDelta{}
+	for issue, grouped := range fullLogDetails.GroupedIssues {
+		for _, row := range groupRows(grouped) {
+			row.Issue = issue
+			rows = append(rows, row)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Issue != rows[j].Issue {
+			return rows[i].Issue < rows[j].Issue
+		}
+		if rows[i].Group != rows[j].Group {
+			return rows[i].Group < rows[j].Group
+		}
+		if rows[i].Base != rows[j].Base {
+			return rows[i].Base > rows[j].Base
+		}
+		return strings.Join(rows[i].Values, "\x00") < strings.Join(rows[j].Values, "\x00")
+	})
+	return rows
+}
+func exportIssuesCsv(fullLogDetails *FullDetails) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	notes := fullLogDetails.Analysis_details.Notes
+	rows := issueCells(fullLogDetails, histogramCounts)
+	header := fullLogDetails.Analysis_details.Header
+	//The notes column is only added to the reports that have issue notes
+	if hasIssueNotes(notes) {
+		header = append(append([]string{}, header...), "Notes")
+		for index, issue := range fullLogDetails.Analysis_details.OrderedIssues {
+			rows[index] = append(rows[index], issueNoteText(notes, issue))
+		}
+	}
+	writer.Write(header)
+	writer.WriteAll(rows)
+	return buffer.Bytes(), writer.Error()
+}
+func exportGroupsCsv(fullLogDetails *FullDetails) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.Write([]string{"Issue", "Group", "Number", "Values"})
+	for _, row := range groupedRows(fullLogDetails) {
+		writer.Write([]string{row.Issue, row.Group, strconv.Itoa(row.Base), strings.Join(row.Values, "; ")})
+	}
+	writer.Flush()
+	return buffer.Bytes(), writer.Error()
+}
+func markdownCell(cell string) string {
+	cell = strings.Replace(cell, "|", "\\|", -1)
+	return strings.Replace(strings.TrimSpace(cell), "\n", "<br>", -1)
+}
+func markdownRow(cells []string) string {
+	escaped := make([]string, len(cells))
+	for index, cell := range cells {
+		escaped[index] = markdownCell(cell)
+	}
+	return "| " + strings.Join(escaped, " | ") + " |\n"
+}
+func exportMarkdown(fullLogDetails *FullDetails) []byte {
+	details := fullLogDetails.Analysis_details
+	var md strings.Builder
+	fmt.Fprintf(&md, "## Log analysis of %s\n\n", markdownCell(details.FileName))
+	fmt.Fprintf(&md, "Config: %s/%s\n\n", markdownCell(details.Platform), markdownCell(details.ConfigName))
+	if len(details.Redactions) > 0 {
+		redactions := make([]string, len(details.Redactions))
+		for index, redaction := range details.Redactions {
+			redactions[index] = fmt.Sprintf("%d %s (%d distinct)", redaction.Count, redaction.Detector, redaction.Distinct)
+		}
+		fmt.Fprintf(&md, "Redacted: %s\n\n", strings.Join(redactions, ", "))
+	}
+	md.WriteString(markdownRow(details.Header))
+	md.WriteString(strings.Repeat("| --- ", len(details.Header)) + "|\n")
+	for _, row := range issueCells(fullLogDetails, histogramSparkline) {
+		md.WriteString(markdownRow(row))
+	}
+	issues := make([]string, 0, len(fullLogDetails.GroupedIssues))
+	for issue := range fullLogDetails.GroupedIssues {
+		issues = append(issues, issue)
+	}
+	sort.Strings(issues)
+	rows := groupedRows(fullLogDetails)
+	for _, issue := range issues {
+		fmt.Fprintf(&md, "\n### %s\n\n", markdownCell(issue))
+		md.WriteString(markdownRow([]string{"Group", "Number", "Values"}))
+		md.WriteString("| --- | --- | --- |\n")
+		for _, row := range rows {
+			if row.Issue == issue {
+				md.WriteString(markdownRow([]string{row.Group, strconv.Itoa(row.Base), strings.Join(row.Values, ", ")}))
+			}
+		}
+	}
+	if notes := details.Notes.All(); len(notes) > 0 {
+		md.WriteString("\n### Notes\n\n")
+		for _, note := range notes {
+			if note.Issue != "" {
+				fmt.Fprintf(&md, "- **%s**: %s\n", markdownCell(note.Issue), markdownCell(note.Text))
+			} else {
+				fmt.Fprintf(&md, "- %s: %s\n", note.Lines(), markdownCell(note.Text))
+			}
+		}
+	}
+	if len(details.UnknownErrors) > 0 {
+		md.WriteString("\n### Unknown errors\n\n")
+		md.WriteString(markdownRow([]string{"Template", "Number", "First", "Last"}))
+		md.WriteString("| --- | --- | --- | --- |\n")
+		for _, unknown := range details.UnknownErrors {
+			md.WriteString(markdownRow([]string{"`" + unknown.Template + "`", strconv.Itoa(unknown.Count), naIfEmpty(unknown.First), naIfEmpty(unknown.Last)}))
+		}
+	}
+	if len(details.StackTraces) > 0 {
+		md.WriteString("\n### Stack traces\n\n")
+		md.WriteString(markdownRow([]string{"Exception", "Top frame", "Number", "First", "Last"}))
+		md.WriteString("| --- | --- | --- | --- | --- |\n")
+		for _, trace := range details.StackTraces {
+			classes := make([]string, len(trace.Chain))
+			for index, exception := range trace.Chain {
+				classes[index] = exception.Class
+			}
+			top_frame := ""
+			if frames := trace.RootCause().Frames; len(frames) > 0 {
+				top_frame = "`" + frames[0] + "`"
+			}
+			md.WriteString(markdownRow([]string{strings.Join(classes, " caused by "), naIfEmpty(top_frame), strconv.Itoa(trace.Count), naIfEmpty(trace.First), naIfEmpty(trace.Last)}))
+		}
+	}
+	if len(details.Anrs) > 0 {
+		md.WriteString("\n### ANRs\n\n")
+		md.WriteString(markdownRow([]string{"Process", "Reason", "Main thread", "Held locks", "Waiting threads"}))
+		md.WriteString("| --- | --- | --- | --- | --- |\n")
+		for _, anr := range details.Anrs {
+			main_thread := anr.Main.State
+			if anr.Main.Waiting != "" {
+				main_thread += ", " + anr.Main.Waiting + " held by " + anr.Main.HeldBy
+			}
+			if len(anr.Main.Frames) > 0 {
+				main_thread += "\n`" + anr.Main.Frames[0] + "`"
+			}
+			locks := make([]string, len(anr.Locks))
+			for index, lock := range anr.Locks {
+				locks[index] = fmt.Sprintf("<%s> held by %s", lock.Address, lock.Holder)
+			}
+			waiting := make([]string, len(anr.Waiting))
+			for index, thread := range anr.Waiting {
+				waiting[index] = thread.Label()
+			}
+			md.WriteString(markdownRow([]string{naIfEmpty(anr.Process), naIfEmpty(anr.Reason), naIfEmpty(main_thread), naIfEmpty(strings.Join(locks, "\n")), naIfEmpty(strings.Join(waiting, "\n"))}))
+		}
+	}
+	if len(details.Tombstones) > 0 {
+		md.WriteString("\n### Native crashes\n\n")
+		md.WriteString(markdownRow([]string{"Process", "Signal", "Fault address", "Abort message", "Top frame"}))
+		md.WriteString("| --- | --- | --- | --- | --- |\n")
+		for _, tombstone := range details.Tombstones {
+			top_frame := ""
+			if len(tombstone.Frames) > 0 {
+				top_frame = "`" + tombstone.Frames[0].Library + " " + tombstone.Frames[0].Function + "`"
+			}
+			md.WriteString(markdownRow([]string{naIfEmpty(tombstone.Process), naIfEmpty(tombstone.Signal), naIfEmpty(tombstone.FaultAddr), naIfEmpty(tombstone.AbortMessage), naIfEmpty(top_frame)}))
+		}
+	}
+	return []byte(md.String())
+}
+func naIfEmpty(value string) string {
+	if value == "" {
+		return "N/A"
+	}
+	return value
+}
+
+// exportHtml renders a single file report, the stylesheet is inlined so that it opens offline
+func exportHtml(fullLogDetails *FullDetails) ([]byte, error) {
+	styles, err := ioutil.ReadFile("assets/styles.css")
+	if err != nil {
+		return nil, err
+	}
+	export_template, err := template.ParseFiles("templates/export.html", "templates/histogram.html")
+	if err != nil {
+		return nil, err
+	}
+	detail_lines := make(map[string]string)
+	for _, issue := range fullLogDetails.Analysis_details.OrderedIssues {
+		detail_lines[issue] = linesSummary(issueRefs(fullLogDetails, issue))
+	}
+	var buffer bytes.Buffer
+	err = export_template.Execute(&buffer, struct {
+		AnalysisDetails
+		Styles      template.CSS
+		DetailLines map[string]string
+		Groups      []GroupDelta
+		NoteList    []Note
+	}{
+		fullLogDetails.Analysis_details,
+		template.CSS(styles),
+		detail_lines,
+		groupedRows(fullLogDetails),
+		fullLogDetails.Analysis_details.Notes.All(),
+	})
+	return buffer.Bytes(), err
+}
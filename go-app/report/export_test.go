@@ -0,0 +1,25 @@
+package report
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// The download name of a report is quoted, or encoded when it is not ASCII
+func TestExportFileName(t *testing.T) {
+	for file, expected := range map[string]string{
+		"bugreport.txt":     `attachment; filename=bugreport.report.md`,
+		`my "wifi" log.txt`: `attachment; filename="my \"wifi\" log.report.md"`,
+		"journal-é.txt":     `attachment; filename*=utf-8''journal-%C3%A9.report.md`,
+	} {
+		fullLogDetails := &FullDetails{}
+		fullLogDetails.Analysis_details.FileName = file
+		recorder := httptest.NewRecorder()
+		if err := ExportReport(recorder, fullLogDetails, "md", ""); err != nil {
+			t.Fatal(err)
+		}
+		if disposition := recorder.Header().Get("Content-Disposition"); disposition != expected {
+			t.Errorf("%s: %s", file, disposition)
+		}
+	}
+}
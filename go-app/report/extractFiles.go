@@ -9,16 +9,34 @@ import (
 	"net/http"
 	"path/filepath"
 	"radar-log-parser/go-app/utilities"
+	"strconv"
 
 	"github.com/PuerkitoBio/goquery"
 	"gopkg.in/yaml.v2"
 )
 
 func extractConfig(cfgName string, bucket string, cfgFile *Config) error {
+	generation, err := utilities.GetObjectGeneration(bucket, cfgName)
+	if err != nil {
+		return err
+	}
+	if cached, ok := getCachedConfig(bucket, cfgName, generation); ok {
+		*cfgFile = cached
+		return nil
+	}
 	cfg_data, err := utilities.DownloadFile(nil, bucket, cfgName)
 	if err != nil {
 		return err
 	}
+	if err := readConfig(cfg_data, cfgFile); err != nil {
+		return err
+	}
+	setCachedConfig(bucket, cfgName, generation, *cfgFile)
+	return nil
+}
+
+// readConfig fills the config from its yaml and compiles its patterns
+func readConfig(cfg_data []byte, cfgFile *Config) error {
 	cfg := &ConfigInterface{}
 	if err := yaml.Unmarshal(cfg_data, cfg); err != nil {
 		return err
@@ -26,22 +44,43 @@ func extractConfig(cfgName string, bucket string, cfgFile *Config) error {
 	cfgFile.IssuesGeneralFields.Details = cfg.IssuesGeneralFields.Details
 	cfgFile.IssuesGeneralFields.Log_level = cfg.IssuesGeneralFields.Log_level
 	cfgFile.IssuesGeneralFields.Number = cfg.IssuesGeneralFields.Number
+	cfgFile.IssuesGeneralFields.Pid = cfg.IssuesGeneralFields.Pid
+	cfgFile.IssuesGeneralFields.Tid = cfg.IssuesGeneralFields.Tid
+	cfgFile.IssuesGeneralFields.Tag = cfg.IssuesGeneralFields.Tag
 	cfgFile.IssuesGeneralFields.OtherFields = cfg.IssuesGeneralFields.OtherFields
 	cfgFile.IssuesGeneralFields.Timestamp = cfg.IssuesGeneralFields.Timestamp
 	cfgFile.Priority = cfg.Priority
-	cfgFile.SpecificProcess = cfg.SpecificProcess
-	cfgFile.ImportantEvents = cfg.ImportantEvents
+	cfgFile.SpecificProcess = extractMatchers(cfg.SpecificProcess)
+	cfgFile.ImportantEvents = extractEvents(cfg.ImportantEvents)
+	cfgFile.Lifecycle = cfg.Lifecycle
+	cfgFile.Redaction.Disabled = cfg.Redaction.Disabled
+	cfgFile.Redaction.Patterns = cfg.Redaction.Patterns
+	cfgFile.LogLevels.Pattern = cfg.LogLevels.Pattern
+	cfgFile.LogLevels.Levels = cfg.LogLevels.Levels
+	cfgFile.LogLevels.Errors = cfg.LogLevels.Errors
+	cfgFile.StackTraces = cfg.StackTraces
 	cfgFile.Issues = make(map[string]Issue)
 	for issue_name, _ := range cfg.Issues {
 		cfgFile.Issues[issue_name] = extract_issues_content(cfg.Issues[issue_name])
 	}
-	return nil
+	var err error
+	cfgFile.matchers, err = compileConfig(cfgFile)
+	return err
 }
 func extract_issues_content(issue interface{}) Issue {
 	myIssues := Issue{}
-	myIssues.specific_process = make(map[string]string)
+	myIssues.specific_process = make(map[string]Matcher)
 	myIssues.additional_fields = make(map[string]string)
 	for issue_key, issue_value := range issue.(map[interface{}]interface{}) {
+		//The literals are a string or a list, like in extractMatcher
+		switch issue_key {
+		case "contains", "any_of":
+			myIssues.contains = append(myIssues.contains, extractLiterals(issue_value)...)
+			continue
+		case "prefix":
+			myIssues.prefix = append(myIssues.prefix, extractLiterals(issue_value)...)
+			continue
+		}
 		switch issue_value.(type) {
 		case string:
 			switch issue_key {
@@ -51,12 +90,32 @@ func extract_issues_content(issue interface{}) Issue {
 				myIssues.detailing_mode = issue_value.(string)
 			case "grouping":
 				myIssues.grouping = issue_value.(string)
+			case "max_rate":
+				myIssues.max_rate = issue_value.(string)
+			case "rate_window":
+				myIssues.rate_window = issue_value.(string)
+			case "context_window":
+				myIssues.context_window = issue_value.(string)
+			}
+		case int:
+			switch issue_key {
+			case "min_count":
+				myIssues.min_count = issue_value.(int)
+			case "max_rate":
+				myIssues.max_rate = strconv.Itoa(issue_value.(int))
+			case "context_before":
+				myIssues.context_before = issue_value.(int)
+			case "context_after":
+				myIssues.context_after = issue_value.(int)
+			}
+		case float64:
+			if issue_key == "max_rate" {
+				myIssues.max_rate = strconv.FormatFloat(issue_value.(float64), 'f', -1, 64)
 			}
-
 		case map[interface{}]interface{}:
 			for name, value := range issue_value.(map[interface{}]interface{}) {
 				if issue_key == "specific_process" {
-					myIssues.specific_process[name.(string)] = value.(string)
+					myIssues.specific_process[name.(string)] = extractMatcher(value)
 				} else {
 					myIssues.additional_fields[name.(string)] = value.(string)
 				}
@@ -66,35 +125,95 @@ func extract_issues_content(issue interface{}) Issue {
 	}
 	return myIssues
 }
+
+// extractMatcher reads a pattern that is either a regex string or a map of
+// regex, contains, prefix and any_of entries
+func extractMatcher(value interface{}) Matcher {
+	matcher := Matcher{}
+	switch value.(type) {
+	case string:
+		matcher.Regex = value.(string)
+	case map[interface{}]interface{}:
+		for key, entry := range value.(map[interface{}]interface{}) {
+			switch key {
+			case "regex":
+				matcher.Regex, _ = entry.(string)
+			case "contains":
+				matcher.Contains = append(matcher.Contains, extractLiterals(entry)...)
+			case "prefix":
+				matcher.Prefix = append(matcher.Prefix, extractLiterals(entry)...)
+			case "any_of":
+				matcher.Contains = append(matcher.Contains, extractLiterals(entry)...)
+			}
+		}
+	}
+	return matcher
+}
+func extractMatchers(values map[string]interface{}) map[string]Matcher {
+	matchers := make(map[string]Matcher)
+	for name, value := range values {
+		matchers[name] = extractMatcher(value)
+	}
+	return matchers
+}
+func extractLiterals(value interface{}) []string {
+	switch value.(type) {
+	case string:
+		return []string{value.(string)}
+	case []interface{}:
+		literals := []string{}
+		for _, literal := range value.([]interface{}) {
+			if literal, ok := literal.(string); ok {
+				literals = append(literals, literal)
+			}
+		}
+		return literals
+	}
+	return nil
+}
 func uploadLogFile(w http.ResponseWriter, r *http.Request, project_id string, region_id string) (string, *string, string, string, error) {
+	cfg_file, selectedBucket, err := selectedConfig(r, project_id, region_id)
+	if err != nil {
+		return "", nil, cfg_file, selectedBucket, err
+	}
+	content, fName, err := readLogFile(r, "myFile")
+	if err != nil {
+		return "", nil, cfg_file, selectedBucket, err
+	}
+	return content, fName, cfg_file, selectedBucket, nil
+}
+func selectedConfig(r *http.Request, project_id string, region_id string) (string, string, error) {
 	r.ParseMultipartForm(10 << 20)
 	cfg_file := r.FormValue("selectedFile")
 	res, err := http.Get("https://" + project_id + "." + region_id + "." + "r.appspot.com/" + r.URL.Path)
 	if err != nil {
-		return "", nil, cfg_file, "", err
+		return cfg_file, "", err
 	}
 	defer res.Body.Close()
 	if res.StatusCode != 200 {
-		return "", nil, cfg_file, "", err
+		return cfg_file, "", err
 	}
 	doc, err := goquery.NewDocumentFromReader(res.Body)
 	if err != nil {
-		return "", nil, cfg_file, "", err
+		return cfg_file, "", err
 	}
 	selectedBucket, found := doc.Find("optgroup").Attr("label")
 	if !found {
-		return "", nil, cfg_file, "", err
+		return cfg_file, "", err
 	}
-	file, handler, err := r.FormFile("myFile")
+	return cfg_file, selectedBucket, nil
+}
+func readLogFile(r *http.Request, field string) (string, *string, error) {
+	file, handler, err := r.FormFile(field)
 	if err != nil {
-		return "", nil, cfg_file, selectedBucket, err
+		return "", nil, err
 	}
+	defer file.Close()
 	content, err := extractLogContent(file, handler)
 	if err != nil {
-		return "", nil, cfg_file, selectedBucket, err
+		return "", nil, err
 	}
-	defer file.Close()
-	return content, &handler.Filename, cfg_file, selectedBucket, nil
+	return content, &handler.Filename, nil
 }
 func extractLogContent(file multipart.File, handler *multipart.FileHeader) (string, error) {
 	if filepath.Ext(handler.Filename) != ".gz" && filepath.Ext(handler.Filename) != ".txt" {
@@ -0,0 +1,166 @@
+package report
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const defaultGroupTop = 50
+
+// groupTopChoices are the sizes offered for the top rows, 0 shows every row
+var groupTopChoices = []int{10, 50, 100, 500, 0}
+
+// GroupTable is the table of a grouped issue, sorted, filtered and cut to its Top rows.
+// The columns are the group, the count and then the other captured values
+type GroupTable struct {
+	Issue      string
+	Columns    []string
+	Rows       []GroupRow
+	Others     GroupRow
+	OtherRows  int
+	Total      int
+	Sort       int
+	Desc       bool
+	Filters    []string
+	Top        int
+	TopChoices []int
+}
+
+// GroupRow is one row of Group_content, Row is its index in the group for the click-through to its lines
+type GroupRow struct {
+	Group  string
+	Row    int
+	Count  int
+	Values []string
+	Known  KnownSignature
+}
+
+// cell returns the text of a column, the count is in column 1
+func (row GroupRow) cell(column int) string {
+	switch {
+	case column == 0:
+		return row.Group
+	case column == 1:
+		return strconv.Itoa(row.Count)
+	case column-2 < len(row.Values):
+		return row.Values[column-2]
+	}
+	return ""
+}
+
+// buildGroupTable sorts by column, numerically when both values are numbers, and keeps the rows whose
+// columns contain every filter. The rows past top are summed in Others
+func buildGroupTable(issue string, grouped GroupedStruct, sort_column int, desc bool, filters []string, top int) GroupTable {
+	table := GroupTable{Issue: issue, Sort: sort_column, Desc: desc, Top: top, TopChoices: groupTopChoices}
+	table.Columns = []string{"Group", "Number"}
+	for index, name := range grouped.Group_names {
+		if index == 1 && name != "" {
+			table.Columns[0] = name
+		}
+		if index > 1 {
+			if name == "" {
+				name = "Value " + strconv.Itoa(index-1)
+			}
+			table.Columns = append(table.Columns, name)
+		}
+	}
+	if table.Sort < 0 || table.Sort >= len(table.Columns) {
+		table.Sort, table.Desc = 1, true
+	}
+	table.Filters = make([]string, len(table.Columns))
+	copy(table.Filters, filters)
+	rows := []GroupRow{}
+	for group, contents := range grouped.Group_content {
+		for index, values := range contents {
+			row := GroupRow{Group: group, Row: index, Count: grouped.Group_count[group][index], Values: values}
+			if table.keep(row) {
+				rows = append(rows, row)
+			}
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if less, equal := compareCells(rows[i].cell(table.Sort), rows[j].cell(table.Sort)); !equal {
+			return less != table.Desc
+		}
+		//Ties keep a stable order: the biggest count, then the group and its row
+		if rows[i].Count != rows[j].Count {
+			return rows[i].Count > rows[j].Count
+		}
+		if rows[i].Group != rows[j].Group {
+			return rows[i].Group < rows[j].Group
+		}
+		return rows[i].Row < rows[j].Row
+	})
+	table.Total = len(rows)
+	if top > 0 && len(rows) > top {
+		for _, row := range rows[top:] {
+			table.Others.Count += row.Count
+		}
+		table.OtherRows = len(rows) - top
+		rows = rows[:top]
+	}
+	table.Rows = rows
+	return table
+}
+func (table GroupTable) keep(row GroupRow) bool {
+	for column, filter := range table.Filters {
+		if filter != "" && !strings.Contains(strings.ToLower(row.cell(column)), strings.ToLower(filter)) {
+			return false
+		}
+	}
+	return true
+}
+func compareCells(a string, b string) (bool, bool) {
+	a_number, a_err := strconv.ParseFloat(a, 64)
+	b_number, b_err := strconv.ParseFloat(b, 64)
+	if a_err == nil && b_err == nil {
+		return a_number < b_number, a_number == b_number
+	}
+	return a < b, a == b
+}
+
+// Query returns the query of the table with another sort column and top, -1 keeps the current one.
+// Sorting again on the sorted column reverses the order
+func (table GroupTable) Query(sort_column int, top int) string {
+	query := url.Values{}
+	desc := table.Desc
+	if sort_column < 0 {
+		sort_column = table.Sort
+	} else if sort_column == table.Sort {
+		desc = !table.Desc
+	} else {
+		//Counts are read from the biggest, text from a to z
+		desc = sort_column == 1
+	}
+	if top < 0 {
+		top = table.Top
+	}
+	query.Set("sort", strconv.Itoa(sort_column))
+	query.Set("desc", strconv.FormatBool(desc))
+	query.Set("top", strconv.Itoa(top))
+	for column, filter := range table.Filters {
+		if filter != "" {
+			query.Set("f"+strconv.Itoa(column), filter)
+		}
+	}
+	return "?" + query.Encode()
+}
+
+// MarkKnown returns the link that prefills the known signatures form with the issue and the fields of a row
+func (table GroupTable) MarkKnown(row GroupRow) string {
+	query := url.Values{}
+	query.Set("issue", table.Issue)
+	query["fields"] = rowSignature(row.Group, row.Values)
+	return "/signatures?" + query.Encode()
+}
+
+// Cells returns the row in the order of the columns
+func (table GroupTable) Cells(row GroupRow) []string {
+	cells := make([]string, len(table.Columns))
+	for column := range cells {
+		cells[column] = row.cell(column)
+	}
+	return cells
+}
@@ -0,0 +1,47 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Columns sort numerically when both cells are numbers, filters ignore the case and the rows past top go to Others
+func TestBuildGroupTableRows(t *testing.T) {
+	grouped := GroupedStruct{
+		Group_names:   []string{"", "iface", "ssid", ""},
+		Group_content: map[string][][]string{"wlan0": {{"home", "3"}, {"work", "12"}, {"cafe", "3"}}, "wlan1": {{"home", "20"}}},
+		Group_count:   map[string][]int{"wlan0": {5, 2, 9}, "wlan1": {1}},
+	}
+	rowsOf := func(table GroupTable) [][]string {
+		rows := [][]string{}
+		for _, row := range table.Rows {
+			rows = append(rows, table.Cells(row))
+		}
+		return rows
+	}
+	table := buildGroupTable("Disconnect", grouped, -1, false, nil, 0)
+	if !reflect.DeepEqual(table.Columns, []string{"iface", "Number", "ssid", "Value 2"}) || table.Sort != 1 || !table.Desc {
+		t.Errorf("columns %v, sort %d desc %t", table.Columns, table.Sort, table.Desc)
+	}
+	expected := [][]string{{"wlan0", "9", "cafe", "3"}, {"wlan0", "5", "home", "3"}, {"wlan0", "2", "work", "12"}, {"wlan1", "1", "home", "20"}}
+	if rows := rowsOf(table); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("by count %v", rows)
+	}
+	if rows := rowsOf(buildGroupTable("Disconnect", grouped, 3, false, nil, 0)); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("by the numeric value %v", rows)
+	}
+	filtered := buildGroupTable("Disconnect", grouped, 1, true, []string{"", "", "HOME"}, 0)
+	if rows := rowsOf(filtered); !reflect.DeepEqual(rows, [][]string{expected[1], expected[3]}) {
+		t.Errorf("filtered on the ssid %v", rows)
+	}
+	top := buildGroupTable("Disconnect", grouped, 1, true, nil, 2)
+	if len(top.Rows) != 2 || top.Total != 4 || top.OtherRows != 2 || top.Others.Count != 3 {
+		t.Errorf("top 2: %d rows of %d, others %d rows %d matches", len(top.Rows), top.Total, top.OtherRows, top.Others.Count)
+	}
+	if query := filtered.Query(2, 10); query != "?desc=false&f2=HOME&sort=2&top=10" {
+		t.Errorf("query %s", query)
+	}
+	if query := filtered.Query(1, -1); query != "?desc=false&f2=HOME&sort=1&top=0" {
+		t.Errorf("query on the sorted column %s", query)
+	}
+}
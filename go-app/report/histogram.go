@@ -0,0 +1,117 @@
+package report
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+type Histogram struct {
+	Start        time.Time
+	End          time.Time
+	Bucket_width time.Duration
+	Counts       []int
+	Max          int
+}
+
+const maxHistogramBuckets = 40
+
+var (
+	timestampLayouts = []string{"01-02 15:04:05", "2006-01-02 15:04:05-0700", "2006-01-02 15:04:05", "2006-01-02T15:04:05Z07:00", "2006-01-02T15:04:05", "Jan _2 15:04:05", "15:04:05"}
+	bucketWidths     = []time.Duration{time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second, 15 * time.Second, 30 * time.Second,
+		time.Minute, 2 * time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+		time.Hour, 2 * time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour}
+)
+
+func (h Histogram) BucketStart(index int) string {
+	return h.Start.Add(h.Bucket_width * time.Duration(index)).Format("15:04:05")
+}
+func (h Histogram) Height(index int) int {
+	if h.Max == 0 {
+		return 0
+	}
+	return h.Counts[index] * 100 / h.Max
+}
+func parseTimestamp(timestamp string) (time.Time, bool) {
+	timestamp = strings.TrimSpace(timestamp)
+	for _, layout := range timestampLayouts {
+		t, err := time.Parse(layout, timestamp)
+		if err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+func matchTimes(timestamp_rgx *regexp.Regexp, logs []string) []time.Time {
+	if timestamp_rgx == nil {
+		return nil
+	}
+	times := make([]time.Time, 0, len(logs))
+	for _, log := range logs {
+		if t, ok := parseTimestamp(timestamp_rgx.FindString(log)); ok {
+			times = append(times, t)
+		}
+	}
+	return times
+}
+func logTimeSpan(timestamp_rgx *regexp.Regexp, fContent string) (time.Time, time.Time, bool) {
+	start, found_start := time.Time{}, false
+	end, found_end := time.Time{}, false
+	contentSlice := strings.Split(fContent, "\n")
+	for _, line := range contentSlice {
+		if start, found_start = parseTimestamp(timestamp_rgx.FindString(line)); found_start {
+			break
+		}
+	}
+	for index := len(contentSlice) - 1; index >= 0; index-- {
+		if end, found_end = parseTimestamp(timestamp_rgx.FindString(contentSlice[index])); found_end {
+			break
+		}
+	}
+	return start, end, found_start && found_end && !end.Before(start)
+}
+func buildHistograms(cfgFile *Config, fContent string, issues_times map[string][]time.Time) map[string]Histogram {
+	histograms := make(map[string]Histogram)
+	timestamp_rgx := cfgFile.matchers.timestamp
+	if timestamp_rgx == nil {
+		return histograms
+	}
+	start, end, ok := logTimeSpan(timestamp_rgx, fContent)
+	if !ok {
+		return histograms
+	}
+	for issue_name, times := range issues_times {
+		if len(times) > 0 {
+			histograms[issue_name] = newHistogram(start, end, times)
+		}
+	}
+	return histograms
+}
+func newHistogram(start time.Time, end time.Time, times []time.Time) Histogram {
+	span := end.Sub(start)
+	width := bucketWidths[len(bucketWidths)-1]
+	for _, bucket_width := range bucketWidths {
+		if span/bucket_width < maxHistogramBuckets {
+			width = bucket_width
+			break
+		}
+	}
+	start = start.Truncate(width)
+	histogram := Histogram{
+		Start:        start,
+		End:          end,
+		Bucket_width: width,
+		Counts:       make([]int, int(end.Sub(start)/width)+1),
+	}
+	for _, t := range times {
+		index := int(t.Sub(start) / width)
+		if index < 0 || index >= len(histogram.Counts) {
+			continue
+		}
+		histogram.Counts[index]++
+		if histogram.Counts[index] > histogram.Max {
+			histogram.Max = histogram.Counts[index]
+		}
+	}
+	return histogram
+}
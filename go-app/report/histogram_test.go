@@ -0,0 +1,34 @@
+package report
+
+import (
+	"testing"
+	"time"
+)
+
+// The buckets are the narrowest width that keeps the log span under maxHistogramBuckets
+func TestBuildHistograms(t *testing.T) {
+	content := "10-19 14:00:00.000 I Wifi: scan started\n10-19 14:02:00.000 E Wifi: scan failed\n10-19 14:05:00.000 I Wifi: scan done\n"
+	at := func(timestamp string) time.Time {
+		parsed, _ := parseTimestamp(timestamp)
+		return parsed
+	}
+	issues_times := map[string][]time.Time{
+		"ScanFailed": {at("10-19 14:00:03"), at("10-19 14:00:07"), at("10-19 14:02:00"), at("10-19 14:06:00")},
+		"Unmatched":  {},
+	}
+	cfg := testConfig(t, "IssuesGeneralFields:\n  Timestamp: '\\d{2}-\\d{2} \\d{2}:\\d{2}:\\d{2}'\n")
+	histograms := buildHistograms(cfg, content, issues_times)
+	if _, ok := histograms["Unmatched"]; ok || len(histograms) != 1 {
+		t.Fatalf("histograms %+v", histograms)
+	}
+	histogram := histograms["ScanFailed"]
+	if histogram.Bucket_width != 10*time.Second || len(histogram.Counts) != 31 || histogram.Max != 2 {
+		t.Fatalf("width %s, %d buckets, max %d", histogram.Bucket_width, len(histogram.Counts), histogram.Max)
+	}
+	if histogram.Counts[0] != 2 || histogram.Counts[12] != 1 || histogram.Height(0) != 100 || histogram.Height(12) != 50 || histogram.BucketStart(12) != "14:02:00" {
+		t.Errorf("counts %v", histogram.Counts)
+	}
+	if histograms := buildHistograms(testConfig(t, "Issues: {}\n"), content, issues_times); len(histograms) != 0 {
+		t.Errorf("histograms without a timestamp pattern %+v", histograms)
+	}
+}
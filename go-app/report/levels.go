@@ -0,0 +1,142 @@
+package report
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// defaultErrorLevels are the levels of the unknown errors when the config has no LogLevels.Errors
+var defaultErrorLevels = []string{"E", "F", "Error", "Fatal", "Critical"}
+
+// LogLevel is one level of the LogLevels config, Code is what the log writes for it such as "E" or "error"
+type LogLevel struct {
+	Name string `yaml:"Name"`
+	Code string `yaml:"Code"`
+}
+
+// compileLevels checks the LogLevels config, a level without a code is written with its name
+func compileLevels(levels []LogLevel) ([]LogLevel, []string) {
+	invalid := []string{}
+	compiled := make([]LogLevel, 0, len(levels))
+	names := make(map[string]bool)
+	for index, level := range levels {
+		if level.Name == "" {
+			invalid = append(invalid, fmt.Sprintf("LogLevels.Levels.%d: missing Name", index))
+			continue
+		}
+		if names[level.Name] {
+			invalid = append(invalid, fmt.Sprintf("LogLevels.Levels.%d: duplicate level %q", index, level.Name))
+			continue
+		}
+		names[level.Name] = true
+		if level.Code == "" {
+			level.Code = level.Name
+		}
+		compiled = append(compiled, level)
+	}
+	return compiled, invalid
+}
+
+// lineLevel returns the name of the level of a line, or its code when the config does not declare it
+func (matchers *matcherSet) lineLevel(line string) string {
+	if matchers.level == nil {
+		return ""
+	}
+	code := captureField(matchers.level, line)
+	for _, level := range matchers.levels {
+		if strings.EqualFold(level.Code, code) {
+			return level.Name
+		}
+	}
+	return code
+}
+
+// isLevel tells whether a line has the level with this name or code
+func (matchers *matcherSet) isLevel(line string, name string) bool {
+	return matchers.sameLevel(matchers.lineLevel(line), name)
+}
+
+// sameLevel tells whether a level read by lineLevel is the level with this name or code
+func (matchers *matcherSet) sameLevel(level string, name string) bool {
+	if strings.EqualFold(level, name) {
+		return true
+	}
+	for _, declared := range matchers.levels {
+		if declared.Name == level && strings.EqualFold(declared.Code, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isErrorLevel tells whether a line has one of the LogLevels.Errors levels
+func (matchers *matcherSet) isErrorLevel(line string) bool {
+	level := matchers.lineLevel(line)
+	if level == "" {
+		return false
+	}
+	for _, name := range matchers.error_levels {
+		if matchers.sameLevel(level, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileErrorLevels checks that the LogLevels.Errors are declared levels, by name or code.
+// Without LogLevels.Errors the levels of the unknown errors are defaultErrorLevels
+func compileErrorLevels(errors []string, levels []LogLevel) ([]string, []string) {
+	invalid := []string{}
+	if len(errors) == 0 {
+		return defaultErrorLevels, invalid
+	}
+	if len(levels) == 0 {
+		return errors, invalid
+	}
+	for index, name := range errors {
+		declared := false
+		for _, level := range levels {
+			if strings.EqualFold(level.Name, name) || strings.EqualFold(level.Code, name) {
+				declared = true
+				break
+			}
+		}
+		if !declared {
+			invalid = append(invalid, fmt.Sprintf("LogLevels.Errors.%d: undeclared level %q", index, name))
+		}
+	}
+	return errors, invalid
+}
+
+// levelNames lists the declared levels, or else the levels written in the log, for the level filter
+func levelNames(matchers *matcherSet, log *logLines) []string {
+	names := []string{}
+	if len(matchers.levels) > 0 {
+		for _, level := range matchers.levels {
+			names = append(names, level.Name)
+		}
+		return names
+	}
+	if matchers.level == nil {
+		return names
+	}
+	found := make(map[string]bool)
+	for _, line := range log.lines {
+		if code := captureField(matchers.level, line); code != "" && !found[code] {
+			found[code] = true
+			names = append(names, code)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// compileLevelPattern uses the LogLevels pattern, or the LogLevel field of the issues
+func compileLevelPattern(pattern string, log_level *regexp.Regexp, compile func(string, string) *regexp.Regexp) *regexp.Regexp {
+	if pattern == "" {
+		return log_level
+	}
+	return compile("LogLevels.Pattern", pattern)
+}
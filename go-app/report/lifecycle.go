@@ -0,0 +1,260 @@
+package report
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// Lifecycle event kinds, in the order they are applied when several happen on the same line
+var lifecycleKinds = []string{"start", "crash", "kill", "death"}
+
+// defaultLifecycle is used when the config has no Lifecycle section, it covers Android logcat.
+// Patterns name their captures pid and process, a missing pid is read with the IssuesGeneralFields Pid pattern
+var defaultLifecycle = map[string][]string{
+	"start": {`ActivityManager: Start proc (?P<pid>\d+):(?P<process>[\w.:]+)/`,
+		`ActivityManager: Start proc (?P<process>[\w.:]+) for .*pid=(?P<pid>\d+)`},
+	"death": {`ActivityManager: Process (?P<process>[\w.:]+) \(pid (?P<pid>\d+)\) has died`},
+	"crash": {`AndroidRuntime: FATAL EXCEPTION`,
+		`AndroidRuntime: Process: (?P<process>[\w.:]+), PID: (?P<pid>\d+)`},
+	"kill": {`lowmemorykiller: Kill(?:ing)? '(?P<process>[^']+)' \((?P<pid>\d+)\)`,
+		`lmkd: Kill(?:ing)? '(?P<process>[^']+)' \((?P<pid>\d+)\)`,
+		`ActivityManager: Killing (?P<pid>\d+):(?P<process>[\w.:]+)/\S+ \(adj -?\d+\): (?:low memory|lmk)`},
+}
+
+// ProcessLife is one run of a process, from its start line to its end line. A life seen without
+// its start has a StartLine of 0, a life still running at the end of the log has an EndLine of 0
+type ProcessLife struct {
+	Process   string
+	Pid       string
+	Start     string
+	End       string
+	StartLine int
+	EndLine   int
+	Crashed   bool
+	Killed    bool
+}
+type ProcessSummary struct {
+	Process  string
+	Starts   int
+	Restarts int
+	Crashes  int
+	Kills    int
+	Lives    []ProcessLife
+}
+type Timeline struct {
+	LogSize   int
+	Processes []ProcessSummary
+	Lives     []ProcessLife
+}
+type lifecycleEvent struct {
+	kind    string
+	index   int
+	pid     string
+	process string
+}
+
+func (life ProcessLife) Ending() string {
+	switch {
+	case life.Crashed:
+		return "crashed"
+	case life.Killed:
+		return "killed (low memory)"
+	case life.EndLine == 0:
+		return "running"
+	}
+	return "died"
+}
+
+// Left and Width place a life on the timeline, in percent of the log lines
+func (timeline Timeline) Left(life ProcessLife) int {
+	if life.StartLine == 0 || timeline.LogSize == 0 {
+		return 0
+	}
+	return (life.StartLine - 1) * 100 / timeline.LogSize
+}
+func (timeline Timeline) Width(life ProcessLife) int {
+	end := life.EndLine
+	if end == 0 {
+		end = timeline.LogSize
+	}
+	width := end*100/timeline.LogSize - timeline.Left(life)
+	if width < 1 {
+		return 1
+	}
+	return width
+}
+
+// ProcessAt returns the name of the process running with this pid at a 1-based line
+func (timeline Timeline) ProcessAt(pid string, line int) string {
+	for _, life := range timeline.Lives {
+		if life.Pid == pid && life.StartLine <= line && (life.EndLine == 0 || line <= life.EndLine) {
+			return life.Process
+		}
+	}
+	return ""
+}
+func compileLifecycle(lifecycle map[string][]string, compile func(string, string) *regexp.Regexp) (map[string][]*regexp.Regexp, []string) {
+	if len(lifecycle) == 0 {
+		lifecycle = defaultLifecycle
+	}
+	invalid := []string{}
+	patterns := make(map[string][]*regexp.Regexp)
+	for kind, rgxs := range lifecycle {
+		if !containsLog(lifecycleKinds, kind) {
+			invalid = append(invalid, fmt.Sprintf("Lifecycle.%s: unknown event, expected one of %v", kind, lifecycleKinds))
+			continue
+		}
+		for index, rgx := range rgxs {
+			if rgx_comp := compile(fmt.Sprintf("Lifecycle.%s[%d]", kind, index), rgx); rgx_comp != nil {
+				patterns[kind] = append(patterns[kind], rgx_comp)
+			}
+		}
+	}
+	return patterns, invalid
+}
+
+// buildTimeline follows every process from its start to its death through the lifecycle events of the log
+func buildTimeline(matchers *matcherSet, log *logLines) Timeline {
+	timeline := Timeline{LogSize: len(log.lines)}
+	events := lifecycleEvents(matchers, log)
+	open := make(map[string]int)
+	for _, event := range events {
+		index, running := open[event.pid]
+		if event.kind == "start" {
+			if running {
+				//The pid was reused without a death in the log
+				timeline.Lives[index].EndLine = event.index
+				timeline.Lives[index].End = lineTimestamp(matchers, log, event.index-1)
+			}
+			open[event.pid] = len(timeline.Lives)
+			timeline.Lives = append(timeline.Lives, ProcessLife{Process: event.process, Pid: event.pid, StartLine: event.index + 1, Start: lineTimestamp(matchers, log, event.index)})
+			continue
+		}
+		if !running {
+			if event.kind == "death" && endedLife(timeline.Lives, event.pid) {
+				continue
+			}
+			//The process started before the log
+			index = len(timeline.Lives)
+			open[event.pid] = index
+			timeline.Lives = append(timeline.Lives, ProcessLife{Process: event.process, Pid: event.pid})
+		}
+		life := &timeline.Lives[index]
+		if life.Process == "" {
+			life.Process = event.process
+		}
+		switch event.kind {
+		case "crash":
+			life.Crashed = true
+		case "kill":
+			life.Killed = true
+		}
+		if event.kind != "crash" {
+			life.EndLine = event.index + 1
+			life.End = lineTimestamp(matchers, log, event.index)
+			delete(open, event.pid)
+		}
+	}
+	summaries := make(map[string]*ProcessSummary)
+	for _, life := range timeline.Lives {
+		if life.Process == "" {
+			life.Process = "pid " + life.Pid
+		}
+		summary, ok := summaries[life.Process]
+		if !ok {
+			summary = &ProcessSummary{Process: life.Process}
+			summaries[life.Process] = summary
+		}
+		if life.StartLine > 0 {
+			summary.Starts++
+		}
+		if life.Crashed {
+			summary.Crashes++
+		}
+		if life.Killed {
+			summary.Kills++
+		}
+		summary.Lives = append(summary.Lives, life)
+	}
+	for _, summary := range summaries {
+		if summary.Starts > 1 {
+			summary.Restarts = summary.Starts - 1
+		}
+		timeline.Processes = append(timeline.Processes, *summary)
+	}
+	sort.Slice(timeline.Processes, func(i, j int) bool {
+		if timeline.Processes[i].Crashes+timeline.Processes[i].Kills != timeline.Processes[j].Crashes+timeline.Processes[j].Kills {
+			return timeline.Processes[i].Crashes+timeline.Processes[i].Kills > timeline.Processes[j].Crashes+timeline.Processes[j].Kills
+		}
+		return timeline.Processes[i].Process < timeline.Processes[j].Process
+	})
+	return timeline
+}
+
+// endedLife tells whether the last life of a pid has already ended, a kill or crash is usually followed by a death line
+func endedLife(lives []ProcessLife, pid string) bool {
+	for index := len(lives) - 1; index >= 0; index-- {
+		if lives[index].Pid == pid {
+			return lives[index].EndLine > 0
+		}
+	}
+	return false
+}
+
+// lifecycleEvents returns the lifecycle events of the log in line order
+func lifecycleEvents(matchers *matcherSet, log *logLines) []lifecycleEvent {
+	events := []lifecycleEvent{}
+	content, starts := log.view(nil)
+	for kind, rgxs := range matchers.lifecycle {
+		for _, rgx := range rgxs {
+			pid_group, process_group := rgx.SubexpIndex("pid"), rgx.SubexpIndex("process")
+			for _, loc := range rgx.FindAllStringSubmatchIndex(content, -1) {
+				index := sort.Search(len(starts), func(i int) bool { return starts[i] > loc[0] }) - 1
+				event := lifecycleEvent{kind: kind, index: index}
+				if pid_group > 0 && loc[2*pid_group] >= 0 {
+					event.pid = content[loc[2*pid_group]:loc[2*pid_group+1]]
+				} else if matchers.pid != nil {
+					event.pid = captureField(matchers.pid, log.lines[index])
+				}
+				if process_group > 0 && loc[2*process_group] >= 0 {
+					event.process = content[loc[2*process_group]:loc[2*process_group+1]]
+				}
+				if event.pid != "" {
+					events = append(events, event)
+				}
+			}
+		}
+	}
+	kind_order := make(map[string]int)
+	for order, kind := range lifecycleKinds {
+		kind_order[kind] = order
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].index != events[j].index {
+			return events[i].index < events[j].index
+		}
+		return kind_order[events[i].kind] < kind_order[events[j].kind]
+	})
+	return events
+}
+func lineTimestamp(matchers *matcherSet, log *logLines, index int) string {
+	if matchers.timestamp == nil || index < 0 {
+		return ""
+	}
+	return matchers.timestamp.FindString(log.lines[index])
+}
+
+// labelProcesses names the process of every matched line from its pid, for lines that carry only a pid
+func labelProcesses(occurrences []Occurrence, timeline Timeline, pid_rgx *regexp.Regexp) {
+	if pid_rgx == nil || len(timeline.Lives) == 0 {
+		return
+	}
+	for _, occurrence := range occurrences {
+		for index, line := range occurrence.Lines {
+			if line.Matches > 0 {
+				occurrence.Lines[index].Process = timeline.ProcessAt(captureField(pid_rgx, line.Content), line.Number)
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package report
+
+import (
+	"sort"
+	"strings"
+)
+
+// LineRef locates one match in an analysed log: the file, the 1-based line number and the byte offset of the match
+type LineRef struct {
+	File   string
+	Line   int
+	Offset int
+}
+
+// logLines is a log split in lines, with the byte offset every line starts at.
+// The lines of a log with CRLF endings are kept without their "\r"
+type logLines struct {
+	file    string
+	content string
+	lines   []string
+	offsets []int
+	crlf    bool
+}
+
+// lineMatch is one match of a pattern: the index of its line, its offset in the log and the matched text
+type lineMatch struct {
+	index  int
+	offset int
+	text   string
+}
+
+func splitLines(file string, content string) *logLines {
+	lines := strings.Split(content, "\n")
+	offsets := make([]int, len(lines))
+	offset := 0
+	crlf := false
+	for index, line := range lines {
+		offsets[index] = offset
+		offset += len(line) + 1
+		if strings.HasSuffix(line, "\r") {
+			lines[index] = line[:len(line)-1]
+			crlf = true
+		}
+	}
+	return &logLines{file: file, content: content, lines: lines, offsets: offsets, crlf: crlf}
+}
+func (log *logLines) ref(match lineMatch) LineRef {
+	return LineRef{File: log.file, Line: match.index + 1, Offset: match.offset}
+}
+
+// view returns the content of the given lines, all of them when indexes is nil, and the offset every line starts at in it
+func (log *logLines) view(indexes []int) (string, []int) {
+	if indexes == nil {
+		if !log.crlf {
+			return log.content, log.offsets
+		}
+		//Joined again so that the matches don't see the "\r"
+		return strings.Join(log.lines, "\n"), lineStarts(log.lines)
+	}
+	lines := make([]string, len(indexes))
+	for i, index := range indexes {
+		lines[i] = log.lines[index]
+	}
+	return strings.Join(lines, "\n"), lineStarts(lines)
+}
+func lineStarts(lines []string) []int {
+	starts := make([]int, len(lines))
+	offset := 0
+	for i, line := range lines {
+		starts[i] = offset
+		offset += len(line) + 1
+	}
+	return starts
+}
+
+// matchLines returns the matches found in the given lines, all of them when indexes is nil.
+// The scan must come from the whole log
+func (matcher lineMatcher) matchLines(log *logLines, indexes []int, scan *literalScan) []lineMatch {
+	matches := []lineMatch{}
+	if len(matcher.literals) > 0 {
+		var in_view map[int]bool
+		if indexes != nil {
+			in_view = make(map[int]bool, len(indexes))
+			for _, index := range indexes {
+				in_view[index] = true
+			}
+		}
+		for _, index := range matcher.candidates(scan) {
+			if in_view != nil && !in_view[index] {
+				continue
+			}
+			line := log.lines[index]
+			if matcher.regex == nil {
+				matches = append(matches, lineMatch{index, log.offsets[index], line})
+				continue
+			}
+			for _, loc := range matcher.regex.FindAllStringIndex(line, -1) {
+				matches = append(matches, lineMatch{index, log.offsets[index] + loc[0], line[loc[0]:loc[1]]})
+			}
+		}
+		return matches
+	}
+	if matcher.regex == nil {
+		return matches
+	}
+	content, starts := log.view(indexes)
+	for _, loc := range matcher.regex.FindAllStringIndex(content, -1) {
+		//The line holding the start of the match
+		position := sort.Search(len(starts), func(i int) bool { return starts[i] > loc[0] }) - 1
+		index := position
+		if indexes != nil {
+			index = indexes[position]
+		}
+		matches = append(matches, lineMatch{index, log.offsets[index] + loc[0] - starts[position], content[loc[0]:loc[1]]})
+	}
+	return matches
+}
+
+// matchedLines returns the distinct line indexes of the matches, in order
+func matchedLines(matches []lineMatch) []int {
+	indexes := make([]int, 0, len(matches))
+	for _, match := range matches {
+		if len(indexes) == 0 || indexes[len(indexes)-1] != match.index {
+			indexes = append(indexes, match.index)
+		}
+	}
+	return indexes
+}
+func matchTexts(matches []lineMatch) []string {
+	texts := make([]string, len(matches))
+	for i, match := range matches {
+		texts[i] = match.text
+	}
+	return texts
+}
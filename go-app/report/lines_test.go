@@ -0,0 +1,82 @@
+package report
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// checkRefs checks that the refs of the matches point at their text in the content of the log
+func checkRefs(t *testing.T, log *logLines, matches []lineMatch, lines []int) {
+	t.Helper()
+	if !reflect.DeepEqual(matchedLines(matches), lines) {
+		t.Errorf("%s: matched lines %v, expected %v", log.file, matchedLines(matches), lines)
+	}
+	for _, match := range matches {
+		ref := log.ref(match)
+		if ref.File != log.file || ref.Line != match.index+1 || log.content[ref.Offset:ref.Offset+len(match.text)] != match.text {
+			t.Errorf("%s: ref %+v does not point at %q", log.file, ref, match.text)
+		}
+	}
+}
+
+// The lines of a CRLF log don't keep their "\r", the offsets still index the log as it was uploaded
+func TestSplitLinesCRLF(t *testing.T) {
+	log := splitLines("crlf.txt", "10-19 14:00:01.000 E Wifi: scan failed\r\n10-19 14:00:02.000 I Wifi: scan done\r\n10-19 14:00:03.000 E Wifi: scan failed")
+	if !reflect.DeepEqual(log.lines, []string{"10-19 14:00:01.000 E Wifi: scan failed", "10-19 14:00:02.000 I Wifi: scan done", "10-19 14:00:03.000 E Wifi: scan failed"}) || !reflect.DeepEqual(log.offsets, []int{0, 40, 78}) {
+		t.Fatalf("lines %q, offsets %v", log.lines, log.offsets)
+	}
+	matcher := lineMatcher{regex: regexp.MustCompile(`(?m)Wifi: .*$`)}
+	for _, indexes := range [][]int{nil, {1, 2}} {
+		matches := matcher.matchLines(log, indexes, nil)
+		for _, text := range matchTexts(matches) {
+			if text != "Wifi: scan failed" && text != "Wifi: scan done" {
+				t.Errorf("lines %v: match %q", indexes, text)
+			}
+		}
+		expected := []int{0, 1, 2}
+		if indexes != nil {
+			expected = indexes
+		}
+		checkRefs(t, log, matches, expected)
+	}
+}
+
+// A final line without a newline is matched like the others, a final newline adds no match
+func TestSplitLinesFinalLine(t *testing.T) {
+	matcher := lineMatcher{regex: regexp.MustCompile(`E Wifi: .*`)}
+	for _, content := range []string{"I Wifi: scan done\nE Wifi: scan failed", "I Wifi: scan done\nE Wifi: scan failed\n", "I Wifi: scan done\r\nE Wifi: scan failed\r\n"} {
+		log := splitLines("final.txt", content)
+		for _, indexes := range [][]int{nil, {0, 1}} {
+			matches := matcher.matchLines(log, indexes, nil)
+			if len(matches) != 1 || matches[0].text != "E Wifi: scan failed" {
+				t.Errorf("%q, lines %v: matches %+v", content, indexes, matches)
+				continue
+			}
+			checkRefs(t, log, matches, []int{1})
+		}
+	}
+}
+
+// The refs of the logs of a comparison carry their own file and offsets, with the literals of the config or a regex
+func TestLineRefsOfFiles(t *testing.T) {
+	cfg := testConfig(t, `
+Issues:
+  Fatal:
+    regex: 'FATAL EXCEPTION: \w+'
+    contains: FATAL
+`)
+	logs := []*logLines{
+		splitLines("base.txt", "I ActivityManager: Start proc 1234\nE AndroidRuntime: FATAL EXCEPTION: main\n"),
+		splitLines("target.txt", "I ActivityManager: Start proc 5678\r\nI ActivityManager: Start proc 5690\r\nE AndroidRuntime: FATAL EXCEPTION: main\r\nE AndroidRuntime: FATAL EXCEPTION: worker"),
+	}
+	expected := [][]int{{1}, {2, 3}}
+	for index, log := range logs {
+		literal := cfg.matchers.issues["Fatal"].regex
+		if len(literal.literals) == 0 {
+			t.Fatal("the issue regex has no literal")
+		}
+		checkRefs(t, log, literal.matchLines(log, nil, cfg.matchers.scan(log)), expected[index])
+		checkRefs(t, log, lineMatcher{regex: literal.regex}.matchLines(log, nil, nil), expected[index])
+	}
+}
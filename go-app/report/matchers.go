@@ -0,0 +1,259 @@
+package report
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Matcher is a config pattern: a regex, literals (contains, prefix, any_of) or both.
+// When both are given, the literals select the candidate lines and the regex runs only on them
+type Matcher struct {
+	Regex    string
+	Contains []string
+	Prefix   []string
+}
+
+// matcherSet holds every regex of a config, compiled once when the config is extracted,
+// and one automaton for all of its literals
+type matcherSet struct {
+	timestamp        *regexp.Regexp
+	log_level        *regexp.Regexp
+	level            *regexp.Regexp
+	levels           []LogLevel
+	error_levels     []string
+	pid              *regexp.Regexp
+	tid              *regexp.Regexp
+	tag              *regexp.Regexp
+	other_fields     map[string]*regexp.Regexp
+	specific_process map[string]lineMatcher
+	issues           map[string]issueMatchers
+	important_events map[string]eventMatcher
+	lifecycle        map[string][]*regexp.Regexp
+	redactors        []redactor
+	stack_traces     stackTraceOptions
+	literals         *ahoCorasick
+}
+type issueMatchers struct {
+	regex             lineMatcher
+	grouping          lineMatcher
+	specific_process  map[string]lineMatcher
+	additional_fields map[string]*regexp.Regexp
+	threshold         threshold
+	context           issueContext
+}
+type lineMatcher struct {
+	regex    *regexp.Regexp
+	literals []int
+}
+
+func (matcher lineMatcher) isEmpty() bool {
+	return matcher.regex == nil && len(matcher.literals) == 0
+}
+
+func (matcher lineMatcher) candidates(scan *literalScan) []int {
+	if len(matcher.literals) == 1 {
+		return scan.hits[matcher.literals[0]]
+	}
+	lines := make(map[int]bool)
+	for _, id := range matcher.literals {
+		for _, index := range scan.hits[id] {
+			lines[index] = true
+		}
+	}
+	candidates := make([]int, 0, len(lines))
+	for index := range lines {
+		candidates = append(candidates, index)
+	}
+	sort.Ints(candidates)
+	return candidates
+}
+
+// scan runs the literal automaton over the lines of the log, nil when the config has no literals
+func (matchers *matcherSet) scan(log *logLines) *literalScan {
+	if matchers.literals == nil {
+		return nil
+	}
+	return matchers.literals.scanLines(log.lines)
+}
+
+type cachedConfig struct {
+	generation int64
+	cfg        Config
+}
+
+var (
+	configCache      = make(map[string]cachedConfig)
+	configCacheMutex sync.Mutex
+)
+
+func getCachedConfig(bucket string, cfgName string, generation int64) (Config, bool) {
+	configCacheMutex.Lock()
+	defer configCacheMutex.Unlock()
+	cached, ok := configCache[bucket+"/"+cfgName]
+	if !ok || cached.generation != generation {
+		return Config{}, false
+	}
+	return cached.cfg, true
+}
+func setCachedConfig(bucket string, cfgName string, generation int64, cfg Config) {
+	configCacheMutex.Lock()
+	defer configCacheMutex.Unlock()
+	configCache[bucket+"/"+cfgName] = cachedConfig{generation, cfg}
+}
+
+// compileConfig compiles every pattern of the config and reports all the invalid ones at once
+func compileConfig(cfgFile *Config) (*matcherSet, error) {
+	invalid := []string{}
+	literals := []string{}
+	prefix := []bool{}
+	literal_ids := make(map[string]int)
+	compile := func(location string, rgx string) *regexp.Regexp {
+		if rgx == "" {
+			return nil
+		}
+		rgx_comp, err := regexp.Compile(rgx)
+		if err != nil {
+			invalid = append(invalid, location+": "+err.Error())
+			return nil
+		}
+		return rgx_comp
+	}
+	addLiteral := func(location string, literal string, is_prefix bool) int {
+		if literal == "" {
+			invalid = append(invalid, location+": empty literal")
+			return -1
+		}
+		key := strconv.FormatBool(is_prefix) + literal
+		if id, ok := literal_ids[key]; ok {
+			return id
+		}
+		literal_ids[key] = len(literals)
+		literals = append(literals, literal)
+		prefix = append(prefix, is_prefix)
+		return len(literals) - 1
+	}
+	compileMatcher := func(location string, matcher Matcher) lineMatcher {
+		line_matcher := lineMatcher{regex: compile(location, matcher.Regex)}
+		for _, literal := range matcher.Contains {
+			if id := addLiteral(location+".contains", literal, false); id >= 0 {
+				line_matcher.literals = append(line_matcher.literals, id)
+			}
+		}
+		for _, literal := range matcher.Prefix {
+			if id := addLiteral(location+".prefix", literal, true); id >= 0 {
+				line_matcher.literals = append(line_matcher.literals, id)
+			}
+		}
+		return line_matcher
+	}
+	compileMap := func(location string, rgxs map[string]string) map[string]*regexp.Regexp {
+		rgxs_comp := make(map[string]*regexp.Regexp)
+		for name, rgx := range rgxs {
+			if rgx_comp := compile(location+"."+name, rgx); rgx_comp != nil {
+				rgxs_comp[name] = rgx_comp
+			}
+		}
+		return rgxs_comp
+	}
+	compileMatchers := func(location string, matchers map[string]Matcher) map[string]lineMatcher {
+		line_matchers := make(map[string]lineMatcher)
+		for name, matcher := range matchers {
+			if line_matcher := compileMatcher(location+"."+name, matcher); !line_matcher.isEmpty() {
+				line_matchers[name] = line_matcher
+			}
+		}
+		return line_matchers
+	}
+	matchers := &matcherSet{
+		timestamp:        compile("IssuesGeneralFields.Timestamp", cfgFile.IssuesGeneralFields.Timestamp),
+		log_level:        compile("IssuesGeneralFields.LogLevel", cfgFile.IssuesGeneralFields.Log_level),
+		pid:              compile("IssuesGeneralFields.Pid", cfgFile.IssuesGeneralFields.Pid),
+		tid:              compile("IssuesGeneralFields.Tid", cfgFile.IssuesGeneralFields.Tid),
+		tag:              compile("IssuesGeneralFields.Tag", cfgFile.IssuesGeneralFields.Tag),
+		other_fields:     compileMap("IssuesGeneralFields.OtherFields", cfgFile.IssuesGeneralFields.OtherFields),
+		specific_process: compileMatchers("SpecificProcess", cfgFile.SpecificProcess),
+		important_events: make(map[string]eventMatcher),
+		issues:           make(map[string]issueMatchers),
+	}
+	for event_name, event := range cfgFile.ImportantEvents {
+		location := "ImportantEvents." + event_name
+		severity, err := checkSeverity(event.Severity)
+		if err != nil {
+			invalid = append(invalid, location+": "+err.Error())
+		}
+		if line_matcher := compileMatcher(location, event.Matcher); !line_matcher.isEmpty() {
+			matchers.important_events[event_name] = eventMatcher{line_matcher, event.Category, severity}
+		}
+	}
+	lifecycle, lifecycle_invalid := compileLifecycle(cfgFile.Lifecycle, compile)
+	matchers.lifecycle = lifecycle
+	invalid = append(invalid, lifecycle_invalid...)
+	redactors, redaction_invalid := compileRedaction(cfgFile.Redaction.Disabled, cfgFile.Redaction.Patterns, compile)
+	matchers.redactors = redactors
+	invalid = append(invalid, redaction_invalid...)
+	matchers.level = compileLevelPattern(cfgFile.LogLevels.Pattern, matchers.log_level, compile)
+	levels, levels_invalid := compileLevels(cfgFile.LogLevels.Levels)
+	matchers.levels = levels
+	invalid = append(invalid, levels_invalid...)
+	error_levels, error_levels_invalid := compileErrorLevels(cfgFile.LogLevels.Errors, levels)
+	matchers.error_levels = error_levels
+	invalid = append(invalid, error_levels_invalid...)
+	stack_traces, err := compileStackTraces(cfgFile)
+	if err != nil {
+		invalid = append(invalid, err.Error())
+	}
+	matchers.stack_traces = stack_traces
+	for issue_name, issue := range cfgFile.Issues {
+		location := "Issues." + issue_name
+		issue_matchers := issueMatchers{
+			specific_process:  compileMatchers(location+".specific_process", issue.specific_process),
+			additional_fields: compileMap(location, issue.additional_fields),
+		}
+		literal_matcher := Matcher{Regex: issue.regex, Contains: issue.contains, Prefix: issue.prefix}
+		pattern := "regex"
+		if issue.detailing_mode == "group" {
+			literal_matcher.Regex = issue.grouping
+			pattern = "grouping"
+		}
+		//An issue without a pattern would never match and always show "Not found"
+		if issue.detailing_mode == "group" && issue.grouping == "" {
+			invalid = append(invalid, location+": group issues need a grouping regex, contains and prefix only narrow its lines")
+		} else if literal_matcher.Regex == "" && len(literal_matcher.Contains) == 0 && len(literal_matcher.Prefix) == 0 {
+			invalid = append(invalid, location+": needs a "+pattern+", contains or prefix")
+		}
+		if issue.detailing_mode == "group" {
+			issue_matchers.grouping = compileMatcher(location+".grouping", literal_matcher)
+			//The first group names the rows, the others are their values
+			if group_rgx := issue_matchers.grouping.regex; group_rgx != nil && group_rgx.NumSubexp() < 2 {
+				invalid = append(invalid, location+".grouping: needs a group for the row name and one for each value")
+			}
+		} else {
+			issue_matchers.regex = compileMatcher(location+".regex", literal_matcher)
+		}
+		limit, err := compileThreshold(issue)
+		if err != nil {
+			invalid = append(invalid, location+": "+err.Error())
+		} else if (limit.max_rate > 0 || limit.window > 0) && matchers.timestamp == nil {
+			invalid = append(invalid, location+": max_rate and rate_window need IssuesGeneralFields.Timestamp")
+		}
+		issue_matchers.threshold = limit
+		context, err := compileContext(issue)
+		if err != nil {
+			invalid = append(invalid, location+": "+err.Error())
+		}
+		issue_matchers.context = context
+		matchers.issues[issue_name] = issue_matchers
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return nil, errors.New("Invalid patterns in config: " + strings.Join(invalid, "; "))
+	}
+	if len(literals) > 0 {
+		matchers.literals = newAhoCorasick(literals, prefix)
+	}
+	return matchers, nil
+}
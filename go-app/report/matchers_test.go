@@ -0,0 +1,55 @@
+package report
+
+import (
+	"strings"
+	"testing"
+)
+
+// Issues that could never report anything are rejected when the config is read
+func TestCompileConfigIssuePatterns(t *testing.T) {
+	tests := []struct {
+		issue   string
+		invalid string
+	}{
+		{"regex: '.*FATAL.*'", ""},
+		{"contains: [FATAL, ANR in]", ""},
+		{"any_of: [FATAL, ANR in]", ""},
+		{"prefix: '10-19'", ""},
+		{"context_before: 2", "Issues.Checked: needs a regex, contains or prefix"},
+		{"contains: []", "Issues.Checked: needs a regex, contains or prefix"},
+		{"detailing_mode: group\n    grouping: '(connect) failed ssid=(\\w+)'\n    contains: WifiService", ""},
+		{"detailing_mode: group\n    contains: WifiService", "Issues.Checked: group issues need a grouping regex"},
+		{"detailing_mode: group\n    prefix: '10-19'", "Issues.Checked: group issues need a grouping regex"},
+		{"detailing_mode: group\n    grouping: 'connect failed ssid=(\\w+)'", "Issues.Checked.grouping: needs a group for the row name"},
+	}
+	for _, test := range tests {
+		cfg := &Config{}
+		err := readConfig([]byte("Issues:\n  Checked:\n    "+test.issue+"\n"), cfg)
+		if test.invalid == "" && err != nil {
+			t.Errorf("%q: %v", test.issue, err)
+		} else if test.invalid != "" && (err == nil || !strings.Contains(err.Error(), test.invalid)) {
+			t.Errorf("%q: got %v, expected %q", test.issue, err, test.invalid)
+		}
+	}
+}
+
+// List-valued contains, any_of and prefix keep all their literals
+func TestExtractIssueLiterals(t *testing.T) {
+	cfg := testConfig(t, `
+Issues:
+  Lists:
+    contains: [FATAL, ANR in]
+    any_of: [Watchdog]
+    prefix: ['10-19', '10-20']
+  Strings:
+    contains: FATAL
+    prefix: '10-19'
+`)
+	lists, strs := cfg.Issues["Lists"], cfg.Issues["Strings"]
+	if len(lists.contains) != 3 || len(lists.prefix) != 2 {
+		t.Errorf("lists: contains %q, prefix %q", lists.contains, lists.prefix)
+	}
+	if len(strs.contains) != 1 || strs.contains[0] != "FATAL" || len(strs.prefix) != 1 || strs.prefix[0] != "10-19" {
+		t.Errorf("strings: contains %q, prefix %q", strs.contains, strs.prefix)
+	}
+}
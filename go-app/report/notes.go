@@ -0,0 +1,174 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxNoteLength = 2000
+
+// Note is an investigation note on a line range, from Line to End, or on an issue of the table
+type Note struct {
+	Id      int
+	Line    int
+	End     int
+	Issue   string
+	Text    string
+	Created time.Time
+}
+
+// Notes are shared by the copies of an analysis, so that a note added from any page shows on all of them
+type Notes struct {
+	mutex sync.Mutex
+	next  int
+	List  []Note
+}
+
+func newNotes() *Notes {
+	return &Notes{next: 1, List: []Note{}}
+}
+
+// Lines returns "L12" or "L12-L20"
+func (note Note) Lines() string {
+	if note.End > note.Line {
+		return fmt.Sprintf("L%d-L%d", note.Line, note.End)
+	}
+	return fmt.Sprintf("L%d", note.Line)
+}
+func (notes *Notes) Add(note Note, log_size int) (Note, error) {
+	note.Text = strings.TrimSpace(note.Text)
+	if note.Text == "" {
+		return note, errors.New("The note is empty")
+	}
+	if len(note.Text) > maxNoteLength {
+		return note, fmt.Errorf("The note is longer than %d characters", maxNoteLength)
+	}
+	if note.Issue == "" {
+		if note.End < note.Line {
+			note.End = note.Line
+		}
+		if note.Line < 1 || note.End > log_size {
+			return note, fmt.Errorf("Lines %s are not in the log", note.Lines())
+		}
+	} else {
+		note.Line, note.End = 0, 0
+	}
+	notes.mutex.Lock()
+	defer notes.mutex.Unlock()
+	note.Id = notes.next
+	note.Created = time.Now()
+	notes.next++
+	notes.List = append(notes.List, note)
+	return note, nil
+}
+func (notes *Notes) Delete(id int) bool {
+	notes.mutex.Lock()
+	defer notes.mutex.Unlock()
+	for index, note := range notes.List {
+		if note.Id == id {
+			notes.List = append(notes.List[:index], notes.List[index+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// All returns the issue notes, then the line notes in line order
+func (notes *Notes) All() []Note {
+	if notes == nil {
+		return []Note{}
+	}
+	notes.mutex.Lock()
+	defer notes.mutex.Unlock()
+	all := append([]Note{}, notes.List...)
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Line < all[j].Line })
+	return all
+}
+
+// ForLine returns the notes of the ranges that contain a 1-based line
+func (notes *Notes) ForLine(line int) []Note {
+	found := []Note{}
+	for _, note := range notes.All() {
+		if note.Issue == "" && note.Line <= line && line <= note.End {
+			found = append(found, note)
+		}
+	}
+	return found
+}
+func (notes *Notes) ForIssue(issue string) []Note {
+	found := []Note{}
+	for _, note := range notes.All() {
+		if note.Issue == issue {
+			found = append(found, note)
+		}
+	}
+	return found
+}
+
+// noteTexts returns the text of every note of a line, for the JSON of the raw viewer
+func noteTexts(notes []Note) []string {
+	texts := make([]string, len(notes))
+	for index, note := range notes {
+		texts[index] = note.Lines() + ": " + note.Text
+	}
+	return texts
+}
+
+// attachNotes adds each note to the first of the lines it covers, so that a range note shows once
+func attachNotes(lines []OccurrenceLine, notes *Notes) {
+	if notes == nil {
+		return
+	}
+	shown := make(map[int]bool)
+	for index, line := range lines {
+		for _, note := range notes.ForLine(line.Number) {
+			if !shown[note.Id] {
+				shown[note.Id] = true
+				lines[index].Notes = append(lines[index].Notes, note)
+			}
+		}
+	}
+}
+
+// NoteRequest adds or deletes a note of an analysis from the POST form fields: line, end and text,
+// or issue and text, or delete with the id of the note
+func NoteRequest(w http.ResponseWriter, r *http.Request, fullLogDetails *FullDetails) {
+	r.ParseMultipartForm(10 << 20)
+	notes := fullLogDetails.Analysis_details.Notes
+	if notes == nil {
+		http.Error(w, "No analysis to add notes to", http.StatusBadRequest)
+		return
+	}
+	if value := r.FormValue("delete"); value != "" {
+		id, _ := strconv.Atoi(value)
+		if !notes.Delete(id) {
+			http.Error(w, "No note "+value, http.StatusNotFound)
+		}
+		return
+	}
+	note := Note{Issue: r.FormValue("issue"), Text: r.FormValue("text")}
+	note.Line, _ = strconv.Atoi(r.FormValue("line"))
+	note.End, _ = strconv.Atoi(r.FormValue("end"))
+	if note.Issue != "" {
+		if _, ok := fullLogDetails.Analysis_details.Issues[note.Issue]; !ok {
+			http.Error(w, "Unknown issue "+note.Issue, http.StatusBadRequest)
+			return
+		}
+	}
+	log := splitLines(fullLogDetails.Analysis_details.FileName, fullLogDetails.Analysis_details.RawLog)
+	note, err := notes.Add(note, len(log.lines))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	jsonValue, _ := json.Marshal(note)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonValue)
+}
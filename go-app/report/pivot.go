@@ -0,0 +1,88 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// Pivot is everything logged by the process, or the thread, of one line, with the issue lines highlighted
+type Pivot struct {
+	Issue   string
+	Field   string
+	Value   string
+	Process string
+	Line    int
+	Lines   []OccurrenceLine
+}
+
+// captureField returns the first group of the regex in the line, or the whole match when it has no group
+func captureField(field_rgx *regexp.Regexp, line string) string {
+	match := field_rgx.FindStringSubmatch(line)
+	if len(match) > 1 {
+		return match[1]
+	}
+	if len(match) == 1 {
+		return match[0]
+	}
+	return ""
+}
+
+// buildPivot keeps the lines with the same pid as the line at index, and the same tid too when field is "tid"
+func buildPivot(log *logLines, matchers *matcherSet, field string, index int, issue_refs []LineRef) (Pivot, error) {
+	pivot := Pivot{Field: field, Line: index + 1}
+	if index < 0 || index >= len(log.lines) {
+		return pivot, fmt.Errorf("Line %d is not in the log", index+1)
+	}
+	if matchers == nil {
+		return pivot, errors.New("No config loaded")
+	}
+	var field_rgx *regexp.Regexp
+	switch field {
+	case "pid":
+		field_rgx = matchers.pid
+	case "tid":
+		field_rgx = matchers.tid
+	default:
+		return pivot, fmt.Errorf("Unknown pivot %q", field)
+	}
+	if field_rgx == nil {
+		return pivot, fmt.Errorf("The config has no %s pattern", field)
+	}
+	pivot.Value = captureField(field_rgx, log.lines[index])
+	if pivot.Value == "" {
+		return pivot, fmt.Errorf("Line %d has no %s", index+1, field)
+	}
+	//Thread ids are only compared within the same process
+	pid_rgx, pid := matchers.pid, ""
+	if field == "tid" && pid_rgx != nil {
+		pid = captureField(pid_rgx, log.lines[index])
+	}
+	matches := make(map[int]int)
+	for _, ref := range issue_refs {
+		matches[ref.Line-1]++
+	}
+	for line_index, line := range log.lines {
+		if captureField(field_rgx, line) != pivot.Value || (pid != "" && captureField(pid_rgx, line) != pid) {
+			continue
+		}
+		pivot.Lines = append(pivot.Lines, OccurrenceLine{Number: line_index + 1, Content: line, Matches: matches[line_index]})
+	}
+	return pivot, nil
+}
+
+// issueRefs returns the references of every match of an issue, grouped or not, in line order
+func issueRefs(fullLogDetails *FullDetails, issue_name string) []LineRef {
+	if refs, ok := fullLogDetails.NonGroupedIssues[issue_name]; ok {
+		return refs
+	}
+	refs := []LineRef{}
+	for _, group_refs := range fullLogDetails.GroupedIssues[issue_name].Group_refs {
+		for _, row_refs := range group_refs {
+			refs = append(refs, row_refs...)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Offset < refs[j].Offset })
+	return refs
+}
@@ -0,0 +1,126 @@
+package report
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Redaction tells how many values a detector replaced in a log
+type Redaction struct {
+	Detector string
+	Count    int
+	Distinct int
+}
+type redactor struct {
+	name string
+	rgx  *regexp.Regexp
+}
+
+// builtinRedactors run before the config patterns, in this order. When a pattern has a group,
+// only the group is replaced so that the key in front of a token stays readable
+var builtinRedactors = []struct {
+	name string
+	rgx  string
+}{
+	{"token", `(?i)\b(?:bearer\s+|(?:token|access_token|refresh_token|auth|api_?key|password|passwd|secret|session_?id)["']?\s*[=:]\s*["']?)([A-Za-z0-9._~+/-]{8,}=*)`},
+	{"jwt", `\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`},
+	{"email", `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`},
+	{"account", `(?i)\b(?:account|user|customer)_?id["']?\s*[=:]\s*["']?([\w-]+)`},
+	{"location", `[-+]?\d{1,2}\.\d{4,},\s*[-+]?\d{1,3}\.\d{4,}`},
+	{"ipv6", `\b(?:[0-9a-fA-F]{1,4}:){7}[0-9a-fA-F]{1,4}\b`},
+	{"ipv4", `\b(?:25[0-5]|2[0-4]\d|1?\d?\d)(?:\.(?:25[0-5]|2[0-4]\d|1?\d?\d)){3}\b`},
+}
+
+// redactionKey makes pseudonyms stable across the logs analysed by this server, set REDACTION_KEY
+// to keep them stable across restarts
+var redactionKey = loadRedactionKey()
+
+func loadRedactionKey() []byte {
+	if key := os.Getenv("REDACTION_KEY"); key != "" {
+		return []byte(key)
+	}
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+// compileRedaction returns the built-in detectors that the config does not disable, then the config patterns
+func compileRedaction(disabled []string, patterns map[string]string, compile func(string, string) *regexp.Regexp) ([]redactor, []string) {
+	invalid := []string{}
+	disabled_map := make(map[string]bool)
+	for _, name := range disabled {
+		disabled_map[name] = true
+	}
+	redactors := []redactor{}
+	builtin := make(map[string]bool)
+	for _, detector := range builtinRedactors {
+		builtin[detector.name] = true
+		if !disabled_map[detector.name] && !disabled_map["all"] {
+			redactors = append(redactors, redactor{detector.name, regexp.MustCompile(detector.rgx)})
+		}
+	}
+	for _, name := range disabled {
+		if !builtin[name] && name != "all" {
+			invalid = append(invalid, fmt.Sprintf("Redaction.Disabled: unknown detector %q", name))
+		}
+	}
+	names := make([]string, 0, len(patterns))
+	for name := range patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if rgx_comp := compile("Redaction.Patterns."+name, patterns[name]); rgx_comp != nil {
+			redactors = append(redactors, redactor{name, rgx_comp})
+		}
+	}
+	return redactors, invalid
+}
+
+// redactLog replaces every detected value with a pseudonym such as <email:1a2b3c4d>, the same value
+// always gets the same pseudonym
+func redactLog(redactors []redactor, content string) (string, []Redaction) {
+	redactions := []Redaction{}
+	for _, redactor := range redactors {
+		locs := redactor.rgx.FindAllStringSubmatchIndex(content, -1)
+		if len(locs) == 0 {
+			continue
+		}
+		redaction := Redaction{Detector: redactor.name}
+		distinct := make(map[string]bool)
+		var redacted strings.Builder
+		last := 0
+		for _, loc := range locs {
+			start, end := loc[0], loc[1]
+			if len(loc) > 2 && loc[2] >= 0 {
+				start, end = loc[2], loc[3]
+			}
+			if start < last || start == end {
+				continue
+			}
+			value := content[start:end]
+			redacted.WriteString(content[last:start])
+			redacted.WriteString(pseudonym(redactor.name, value))
+			last = end
+			redaction.Count++
+			distinct[value] = true
+		}
+		redacted.WriteString(content[last:])
+		content = redacted.String()
+		redaction.Distinct = len(distinct)
+		redactions = append(redactions, redaction)
+	}
+	return content, redactions
+}
+func pseudonym(detector string, value string) string {
+	mac := hmac.New(sha256.New, redactionKey)
+	mac.Write([]byte(detector + "\x00" + value))
+	return "<" + detector + ":" + hex.EncodeToString(mac.Sum(nil))[:8] + ">"
+}
@@ -0,0 +1,292 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSearchPageSize = 100
+	maxSearchPageSize     = 1000
+)
+
+// searchFields are the field:value terms of the query language, any other word with a colon is plain text
+var searchFields = map[string]bool{"level": true, "tag": true, "pid": true, "tid": true, "after": true, "before": true}
+
+type SearchResults struct {
+	Query    string
+	Total    int
+	Page     int
+	PageSize int
+	Results  []SearchResult
+}
+
+// SearchResult is one matching line, split in parts so that the matched spans can be highlighted
+type SearchResult struct {
+	Line  int
+	Parts []SearchPart
+}
+type SearchPart struct {
+	Text  string
+	Match bool
+}
+
+// searchTerm is one term of a query: free text, a "quoted phrase", a /regex/ or a field:value filter
+type searchTerm struct {
+	field  string
+	value  string
+	negate bool
+	rgx    *regexp.Regexp
+	clock  time.Duration
+	date   time.Time
+	dated  bool
+}
+
+// parseQuery reads queries such as `level:E tag:Wifi "timeout" after:14:30 before:14:35 -"retrying"`
+func parseQuery(query string) ([]searchTerm, error) {
+	terms := []searchTerm{}
+	for _, token := range splitQuery(query) {
+		term := searchTerm{}
+		if strings.HasPrefix(token, "-") && len(token) > 1 {
+			term.negate = true
+			token = token[1:]
+		}
+		if colon := strings.Index(token, ":"); colon > 0 && searchFields[strings.ToLower(token[:colon])] {
+			term.field = strings.ToLower(token[:colon])
+			token = token[colon+1:]
+		}
+		switch {
+		case term.field == "" && len(token) > 1 && strings.HasPrefix(token, "/") && strings.HasSuffix(token, "/"):
+			rgx, err := regexp.Compile(token[1 : len(token)-1])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid regex %s: %v", token, err)
+			}
+			term.rgx = rgx
+		case len(token) > 1 && strings.HasPrefix(token, "\"") && strings.HasSuffix(token, "\""):
+			token = token[1 : len(token)-1]
+		}
+		term.value = token
+		if term.value == "" && term.rgx == nil {
+			continue
+		}
+		if term.field == "" && term.rgx == nil {
+			term.rgx = regexp.MustCompile("(?i)" + regexp.QuoteMeta(term.value))
+		}
+		if term.field == "after" || term.field == "before" {
+			if err := parseSearchTime(&term); err != nil {
+				return nil, err
+			}
+		}
+		terms = append(terms, term)
+	}
+	if len(terms) == 0 {
+		return nil, errors.New("Empty query")
+	}
+	return terms, nil
+}
+
+// splitQuery splits a query on spaces, except inside quotes and /regexes/
+func splitQuery(query string) []string {
+	tokens := []string{}
+	var token strings.Builder
+	in_quote, in_regex := false, false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\\' && in_regex && i+1 < len(query):
+			token.WriteByte(c)
+			i++
+			c = query[i]
+		case c == '"' && !in_regex:
+			in_quote = !in_quote
+		case c == '/' && !in_quote:
+			current := strings.TrimPrefix(token.String(), "-")
+			if current == "" {
+				in_regex = true
+			} else if in_regex && strings.HasPrefix(current, "/") {
+				in_regex = false
+			}
+		case c == ' ' && !in_quote && !in_regex:
+			if token.Len() > 0 {
+				tokens = append(tokens, token.String())
+				token.Reset()
+			}
+			continue
+		}
+		token.WriteByte(c)
+	}
+	if token.Len() > 0 {
+		tokens = append(tokens, token.String())
+	}
+	return tokens
+}
+
+// parseSearchTime accepts a time of day such as 14:30 or 14:30:05, or a full timestamp
+func parseSearchTime(term *searchTerm) error {
+	for _, layout := range []string{"15:04", "15:04:05", "15:04:05.000"} {
+		if t, err := time.Parse(layout, term.value); err == nil {
+			term.clock = timeOfDay(t)
+			return nil
+		}
+	}
+	if t, ok := parseTimestamp(term.value); ok {
+		term.date, term.dated = t, true
+		return nil
+	}
+	return fmt.Errorf("Invalid time %q in %s:", term.value, term.field)
+}
+func timeOfDay(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+}
+
+// searchLog returns one page of the lines that match every term of the query
+func searchLog(log *logLines, matchers *matcherSet, query string, page int, page_size int) (SearchResults, error) {
+	results := SearchResults{Query: query, Page: page, PageSize: page_size, Results: []SearchResult{}}
+	if page_size <= 0 || page_size > maxSearchPageSize {
+		results.PageSize = defaultSearchPageSize
+	}
+	if page < 0 {
+		results.Page = 0
+	}
+	terms, err := parseQuery(query)
+	if err != nil {
+		return results, err
+	}
+	if matchers == nil {
+		matchers = &matcherSet{}
+	}
+	for _, term := range terms {
+		if err := checkSearchField(matchers, term.field); err != nil {
+			return results, err
+		}
+	}
+	first := results.Page * results.PageSize
+	for index, line := range log.lines {
+		spans, ok := matchLine(matchers, terms, line)
+		if !ok {
+			continue
+		}
+		if results.Total >= first && results.Total < first+results.PageSize {
+			results.Results = append(results.Results, SearchResult{Line: index + 1, Parts: highlightParts(line, spans)})
+		}
+		results.Total++
+	}
+	return results, nil
+}
+func checkSearchField(matchers *matcherSet, field string) error {
+	missing := ""
+	switch field {
+	case "level":
+		//Like isLevel, a LogLevels.Pattern is enough
+		if matchers.level == nil {
+			missing = "LogLevel or LogLevels.Pattern"
+		}
+	case "pid":
+		if matchers.pid == nil {
+			missing = "Pid"
+		}
+	case "tid":
+		if matchers.tid == nil {
+			missing = "Tid"
+		}
+	case "tag":
+		if matchers.tag == nil && matchers.level == nil {
+			missing = "Tag"
+		}
+	case "after", "before":
+		if matchers.timestamp == nil {
+			missing = "Timestamp"
+		}
+	}
+	if missing != "" {
+		return fmt.Errorf("%s: needs a %s pattern in the config", field, missing)
+	}
+	return nil
+}
+
+// matchLine tells whether the line matches every term and returns the spans of the text terms
+func matchLine(matchers *matcherSet, terms []searchTerm, line string) ([][]int, bool) {
+	spans := [][]int{}
+	for _, term := range terms {
+		matched := false
+		switch term.field {
+		case "":
+			locs := term.rgx.FindAllStringIndex(line, -1)
+			matched = len(locs) > 0
+			if matched && !term.negate {
+				spans = append(spans, locs...)
+			}
+		case "level":
+			matched = matchers.isLevel(line, term.value)
+		case "pid":
+			matched = captureField(matchers.pid, line) == term.value
+		case "tid":
+			matched = captureField(matchers.tid, line) == term.value
+		case "tag":
+			matched = strings.Contains(strings.ToLower(lineTag(matchers, line)), strings.ToLower(term.value))
+		case "after", "before":
+			t, ok := parseTimestamp(matchers.timestamp.FindString(line))
+			if !ok {
+				return nil, false
+			}
+			if term.dated {
+				matched = (term.field == "after" && !t.Before(term.date)) || (term.field == "before" && !t.After(term.date))
+			} else {
+				matched = (term.field == "after" && timeOfDay(t) >= term.clock) || (term.field == "before" && timeOfDay(t) <= term.clock)
+			}
+		}
+		if matched == term.negate {
+			return nil, false
+		}
+	}
+	return spans, true
+}
+
+// lineTag returns the tag of a line with the Tag pattern, or else the text between the log level and the next colon
+func lineTag(matchers *matcherSet, line string) string {
+	if matchers.tag != nil {
+		return captureField(matchers.tag, line)
+	}
+	loc := matchers.level.FindStringIndex(line)
+	if loc == nil {
+		return ""
+	}
+	rest := line[loc[1]:]
+	if colon := strings.Index(rest, ":"); colon >= 0 {
+		rest = rest[:colon]
+	}
+	return strings.TrimSpace(rest)
+}
+
+// highlightParts splits a line on the matched spans, overlapping and adjacent spans are merged
+func highlightParts(line string, spans [][]int) []SearchPart {
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	parts := []SearchPart{}
+	last := 0
+	for _, span := range spans {
+		if span[1] <= last || span[0] == span[1] {
+			continue
+		}
+		start := span[0]
+		if start < last {
+			start = last
+		}
+		if start > last {
+			parts = append(parts, SearchPart{Text: line[last:start]})
+		}
+		if start == last && len(parts) > 0 && parts[len(parts)-1].Match {
+			parts[len(parts)-1].Text += line[start:span[1]]
+		} else {
+			parts = append(parts, SearchPart{Text: line[start:span[1]], Match: true})
+		}
+		last = span[1]
+	}
+	if last < len(line) {
+		parts = append(parts, SearchPart{Text: line[last:]})
+	}
+	return parts
+}
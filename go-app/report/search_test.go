@@ -0,0 +1,129 @@
+package report
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitQuery(t *testing.T) {
+	tests := []struct {
+		query  string
+		tokens []string
+	}{
+		{"wifi  timeout", []string{"wifi", "timeout"}},
+		{`"connect failed" -"ssid=home"`, []string{`"connect failed"`, `-"ssid=home"`}},
+		{`tag:"Wifi Service" level:E`, []string{`tag:"Wifi Service"`, "level:E"}},
+		{`/reason=\d+ ms/ -/retry(ing)?/`, []string{`/reason=\d+ ms/`, "-/retry(ing)?/"}},
+		{`/a\/b c/ d`, []string{`/a\/b c/`, "d"}},
+		{`path/to/file "a/b c"`, []string{"path/to/file", `"a/b c"`}},
+	}
+	for _, test := range tests {
+		if tokens := splitQuery(test.query); !reflect.DeepEqual(tokens, test.tokens) {
+			t.Errorf("%q split in %q, expected %q", test.query, tokens, test.tokens)
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		terms []searchTerm
+		err   string
+	}{
+		{query: `Level:E -tag:"Wifi Service"`, terms: []searchTerm{{field: "level", value: "E"}, {field: "tag", value: "Wifi Service", negate: true}}},
+		{query: `ssid:home -"connect failed"`, terms: []searchTerm{{value: "ssid:home"}, {value: "connect failed", negate: true}}},
+		{query: `/reason=\d/ -`, terms: []searchTerm{{value: `/reason=\d/`}, {value: "-"}}},
+		{query: `"" pid:`, err: "Empty query"},
+		{query: "/[/", err: "Invalid regex /[/"},
+		{query: "-/(a/", err: "Invalid regex /(a/"},
+		{query: "after:25:99", err: `Invalid time "25:99" in after:`},
+	}
+	for _, test := range tests {
+		terms, err := parseQuery(test.query)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%q: error %v, expected %q", test.query, err, test.err)
+			}
+			continue
+		}
+		if err != nil || len(terms) != len(test.terms) {
+			t.Errorf("%q: %+v %v", test.query, terms, err)
+			continue
+		}
+		for index, term := range terms {
+			expected := test.terms[index]
+			if term.field != expected.field || term.value != expected.value || term.negate != expected.negate || (term.field == "") != (term.rgx != nil) {
+				t.Errorf("%q: term %d is %+v, expected %+v", test.query, index, term, expected)
+			}
+		}
+	}
+}
+
+func TestSearchLog(t *testing.T) {
+	cfg := testConfig(t, `
+IssuesGeneralFields:
+  Timestamp: '\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{3}'
+  LogLevel: '^\S+ \S+\s+\d+\s+\d+ ([A-Z]) '
+  Pid: '^\S+ \S+\s+(\d+)'
+  Tid: '^\S+ \S+\s+\d+\s+(\d+)'
+`)
+	log := splitLines("search.txt", `10-19 14:29:58.000  1000  1001 I ActivityManager: Start proc com.example.app
+10-19 14:30:02.000  2222  2230 W WifiService: connect failed ssid=home reason=3
+10-19 14:31:10.000  2222  2230 W WifiService: connect failed ssid=work reason=1
+10-19 14:34:00.000  1234  1250 E AndroidRuntime: FATAL EXCEPTION: main
+10-19 14:36:00.000  2222  2231 W WifiService: Connect FAILED ssid=home reason=3`)
+	tests := []struct {
+		query string
+		lines []int
+	}{
+		{"connect failed", []int{2, 3, 5}},
+		{`"connect failed" -"ssid=home"`, []int{3}},
+		{"level:W tag:wifi", []int{2, 3, 5}},
+		{"-level:W", []int{1, 4}},
+		{"tag:AndroidRuntime", []int{4}},
+		{"pid:2222 tid:2230", []int{2, 3}},
+		{"-pid:2222", []int{1, 4}},
+		{"after:14:30 before:14:35", []int{2, 3, 4}},
+		{`after:"10-19 14:31:10.000"`, []int{3, 4, 5}},
+		{`/reason=[13]$/ -/work/`, []int{2, 5}},
+		{"EXCEPTION:", []int{4}},
+	}
+	for _, test := range tests {
+		results, err := searchLog(log, cfg.matchers, test.query, 0, 10)
+		lines := []int{}
+		for _, result := range results.Results {
+			lines = append(lines, result.Line)
+		}
+		if err != nil || results.Total != len(test.lines) || !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("%q: lines %v of %d %v, expected %v", test.query, lines, results.Total, err, test.lines)
+		}
+	}
+	page, _ := searchLog(log, cfg.matchers, "WifiService", 1, 2)
+	if page.Total != 3 || len(page.Results) != 1 || page.Results[0].Line != 5 {
+		t.Errorf("second page %+v", page)
+	}
+	if _, err := searchLog(log, testConfig(t, "").matchers, "pid:2222", 0, 10); err == nil || err.Error() != "pid: needs a Pid pattern in the config" {
+		t.Errorf("pid without a Pid pattern: %v", err)
+	}
+}
+
+func TestHighlightParts(t *testing.T) {
+	line := "connect failed ssid=home reason=3"
+	tests := []struct {
+		spans [][]int
+		parts []SearchPart
+	}{
+		{nil, []SearchPart{{line, false}}},
+		{[][]int{{0, 7}}, []SearchPart{{"connect", true}, {" failed ssid=home reason=3", false}}},
+		{[][]int{{20, 24}, {8, 14}}, []SearchPart{{"connect ", false}, {"failed", true}, {" ssid=", false}, {"home", true}, {" reason=3", false}}},
+		{[][]int{{8, 19}, {15, 24}, {10, 12}}, []SearchPart{{"connect ", false}, {"failed ssid=home", true}, {" reason=3", false}}},
+		{[][]int{{0, 7}, {7, 14}, {3, 3}}, []SearchPart{{"connect failed", true}, {" ssid=home reason=3", false}}},
+		{[][]int{{25, 33}}, []SearchPart{{"connect failed ssid=home ", false}, {"reason=3", true}}},
+	}
+	for _, test := range tests {
+		if parts := highlightParts(line, test.spans); !reflect.DeepEqual(parts, test.parts) {
+			t.Errorf("%v: %+v, expected %+v", test.spans, parts, test.parts)
+		}
+	}
+}
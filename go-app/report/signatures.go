@@ -0,0 +1,254 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"radar-log-parser/go-app/utilities"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const knownSignaturesObject = "known_signatures.json"
+
+// signatureStatuses are the states of a filed bug, a fixed bug that shows up again is worth a look
+var signatureStatuses = []string{"Open", "Fixed", "Won't fix", "Duplicate"}
+
+// KnownSignature maps an issue to a filed bug. Without Fields it matches the whole issue, with Fields it
+// matches the group rows whose normalized group and values are the same. The signature of a stack trace
+// is its exception class as Issue and its hash as Stack
+type KnownSignature struct {
+	Issue   string
+	Fields  []string
+	Stack   string
+	Bug     string
+	Url     string
+	Status  string
+	FixedIn string
+}
+
+// KnownIssue is what an analysis knows about an issue: its signature, and how many of its group rows are known.
+// New is set when the issue matched lines that no signature covers
+type KnownIssue struct {
+	Signature KnownSignature
+	Rows      int
+	KnownRows int
+	New       bool
+}
+
+// KnownReport is the result of matching an analysis with the known signatures, Stacks are the known stack traces
+// by hash. Error is set when the signatures could not be read
+type KnownReport struct {
+	Issues map[string]KnownIssue
+	Stacks map[string]KnownSignature
+	Error  string
+}
+
+// SignatureDB keeps the known signatures in a JSON object of the app bucket, read on first use
+type SignatureDB struct {
+	mutex      sync.Mutex
+	bucket     string
+	object     string
+	loaded     bool
+	signatures []KnownSignature
+}
+
+func NewSignatureDB(bucket string) *SignatureDB {
+	return &SignatureDB{bucket: bucket, object: knownSignaturesObject}
+}
+
+// Key identifies a signature, adding a signature with the same key replaces it
+func (signature KnownSignature) Key() string {
+	return groupKey(append([]string{signature.Issue, signature.Stack}, signature.Fields...))
+}
+func (signature KnownSignature) Known() bool {
+	return signature.Bug != ""
+}
+
+// normalizeFields lowercases the captured values and masks their ids and addresses like the unknown errors,
+// so that a signature still matches when only an address changed. Numbers are kept: a captured reason or
+// error code tells two bugs apart
+func normalizeFields(values []string) []string {
+	normalized := make([]string, len(values))
+	for index, value := range values {
+		value = strings.Join(strings.Fields(value), " ")
+		for _, token := range variableTokens {
+			if token.mask == "<UUID>" || token.mask == "<HEX>" {
+				value = token.rgx.ReplaceAllString(value, token.mask)
+			}
+		}
+		normalized[index] = strings.ToLower(value)
+	}
+	return normalized
+}
+
+// rowSignature returns the fields of a group row, the group then its values
+func rowSignature(group string, values []string) []string {
+	return normalizeFields(append([]string{group}, values...))
+}
+func (db *SignatureDB) load() error {
+	if db.loaded {
+		return nil
+	}
+	content, found, err := utilities.DownloadFileIfExists(db.bucket, db.object)
+	if err != nil {
+		return err
+	}
+	signatures := []KnownSignature{}
+	if found {
+		if err := json.Unmarshal(content, &signatures); err != nil {
+			return fmt.Errorf("%s: %v", db.object, err)
+		}
+	}
+	db.signatures = signatures
+	db.loaded = true
+	return nil
+}
+func (db *SignatureDB) save(signatures []KnownSignature) error {
+	content, err := json.MarshalIndent(signatures, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := utilities.UploadFile(db.bucket, db.object, content); err != nil {
+		return err
+	}
+	db.signatures = signatures
+	return nil
+}
+
+// List returns the signatures sorted by issue, then bug
+func (db *SignatureDB) List() ([]KnownSignature, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	if err := db.load(); err != nil {
+		return nil, err
+	}
+	signatures := append([]KnownSignature{}, db.signatures...)
+	sort.SliceStable(signatures, func(i, j int) bool {
+		if signatures[i].Issue != signatures[j].Issue {
+			return signatures[i].Issue < signatures[j].Issue
+		}
+		return signatures[i].Bug < signatures[j].Bug
+	})
+	return signatures, nil
+}
+
+// Add saves a signature with its fields normalized, replacing the signature with the same key
+func (db *SignatureDB) Add(signature KnownSignature) error {
+	signature.Issue = strings.TrimSpace(signature.Issue)
+	signature.Bug = strings.TrimSpace(signature.Bug)
+	signature.Stack = strings.TrimSpace(signature.Stack)
+	signature.FixedIn = strings.TrimSpace(signature.FixedIn)
+	signature.Url = strings.TrimSpace(signature.Url)
+	if signature.Issue == "" || signature.Bug == "" {
+		return errors.New("A known signature needs an issue name and a bug")
+	}
+	if len(signature.Fields) == 0 {
+		signature.Fields = nil
+	} else {
+		signature.Fields = normalizeFields(signature.Fields)
+	}
+	if signature.Url != "" {
+		link, err := url.Parse(signature.Url)
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") {
+			return fmt.Errorf("The bug link %q is not an http(s) URL", signature.Url)
+		}
+	}
+	if signature.Status == "" {
+		signature.Status = signatureStatuses[0]
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	if err := db.load(); err != nil {
+		return err
+	}
+	signatures := make([]KnownSignature, 0, len(db.signatures)+1)
+	for _, known := range db.signatures {
+		if known.Key() != signature.Key() {
+			signatures = append(signatures, known)
+		}
+	}
+	return db.save(append(signatures, signature))
+}
+func (db *SignatureDB) Delete(key string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	if err := db.load(); err != nil {
+		return err
+	}
+	signatures := make([]KnownSignature, 0, len(db.signatures))
+	for _, known := range db.signatures {
+		if known.Key() != key {
+			signatures = append(signatures, known)
+		}
+	}
+	if len(signatures) == len(db.signatures) {
+		return errors.New("Unknown signature")
+	}
+	return db.save(signatures)
+}
+
+// MatchSignatures marks the issues and the group rows of an analysis that match a known signature,
+// the issues with matches that none covers are flagged as new. The known rows and report are new
+// values, so matching a copy of an analysis leaves the original unchanged
+func MatchSignatures(fullLogDetails *FullDetails, db *SignatureDB) {
+	known_report := KnownReport{Issues: make(map[string]KnownIssue), Stacks: make(map[string]KnownSignature)}
+	fullLogDetails.KnownRows = make(map[string]KnownSignature)
+	signatures, err := db.List()
+	if err != nil {
+		known_report.Error = err.Error()
+		fullLogDetails.Analysis_details.Known = known_report
+		return
+	}
+	by_key := make(map[string]KnownSignature)
+	for _, signature := range signatures {
+		by_key[signature.Key()] = signature
+	}
+	for _, issue := range fullLogDetails.Analysis_details.OrderedIssues {
+		known := KnownIssue{Signature: by_key[KnownSignature{Issue: issue}.Key()]}
+		if grouped, ok := fullLogDetails.GroupedIssues[issue]; ok {
+			for group, contents := range grouped.Group_content {
+				for _, values := range contents {
+					known.Rows++
+					fields := rowSignature(group, values)
+					if signature, ok := by_key[KnownSignature{Issue: issue, Fields: fields}.Key()]; ok {
+						fullLogDetails.KnownRows[rowKey(issue, group, values)] = signature
+						known.KnownRows++
+					}
+				}
+			}
+		}
+		covered := known.Signature.Known() || (known.Rows > 0 && known.KnownRows == known.Rows)
+		known.New = !covered && len(issueRefs(fullLogDetails, issue)) > 0
+		known_report.Issues[issue] = known
+	}
+	for _, trace := range fullLogDetails.Analysis_details.StackTraces {
+		if signature, ok := by_key[trace.signature().Key()]; ok {
+			known_report.Stacks[trace.Hash] = signature
+		}
+	}
+	fullLogDetails.Analysis_details.Known = known_report
+}
+func rowKey(issue string, group string, values []string) string {
+	return groupKey(append([]string{issue, group}, values...))
+}
+
+// SignaturesPage is the data of the known signatures page, Draft prefills the form from a report link
+type SignaturesPage struct {
+	Signatures []KnownSignature
+	Statuses   []string
+	Draft      KnownSignature
+	Error      string
+}
+
+func NewSignaturesPage(db *SignatureDB, draft KnownSignature) SignaturesPage {
+	page := SignaturesPage{Statuses: signatureStatuses, Draft: draft}
+	signatures, err := db.List()
+	if err != nil {
+		page.Error = err.Error()
+	}
+	page.Signatures = signatures
+	return page
+}
@@ -0,0 +1,276 @@
+package report
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultTraceFrames = 5
+	//Lines of other processes that may be interleaved in a trace before it is considered over
+	maxTraceInterleaved = 20
+	//Lines of an exception message before its first frame
+	maxTraceMessageLines = 5
+	maxTraceExampleLines = 60
+)
+
+var (
+	traceHeader   = regexp.MustCompile(`((?:[a-zA-Z_$][\w$]*\.)+[A-Z][\w$]*(?:Exception|Error|Throwable))(?::\s*(.*?))?\s*$`)
+	traceCause    = regexp.MustCompile(`\b(Caused by|Suppressed):\s+`)
+	traceFrame    = regexp.MustCompile(`\bat\s+([\w$.<>/-]+)\(([^()]*)\)\s*$`)
+	traceMore     = regexp.MustCompile(`\.\.\.\s+\d+\s+more\s*$`)
+	traceLine     = regexp.MustCompile(`:\d+\)$`)
+	traceLambda   = regexp.MustCompile(`\$\$(?:Lambda|ExternalSyntheticLambda)[\w$/]*|\blambda\$[\w$]*|\$lambda[-$][\w$-]*`)
+	traceKeywords = []string{"Exception", "Error", "Throwable"}
+)
+
+// StackTrace is one Java or Kotlin exception found Count times, with the same classes and kept frames.
+// Lines are the first lines of every occurrence, Example the lines of the first one
+type StackTrace struct {
+	Hash    string
+	Chain   []TraceException
+	Count   int
+	First   string
+	Last    string
+	Lines   []int
+	Example []string
+	End     int
+}
+
+// TraceException is the exception and then each "Caused by:" of a trace, Frames are the kept frames normalized
+type TraceException struct {
+	Class   string
+	Message string
+	Frames  []string
+}
+
+// StackTraceConfig is the StackTraces section of the config, every field is optional
+type StackTraceConfig struct {
+	Disabled        bool     `yaml:"Disabled"`
+	KeepLineNumbers bool     `yaml:"KeepLineNumbers"`
+	KeepLambdas     bool     `yaml:"KeepLambdas"`
+	AppPackages     []string `yaml:"AppPackages"`
+	MaxFrames       int      `yaml:"MaxFrames"`
+}
+
+// stackTraceOptions are the StackTraces of the config, line numbers and lambdas are stripped unless kept
+type stackTraceOptions struct {
+	disabled          bool
+	keep_line_numbers bool
+	keep_lambdas      bool
+	app_packages      []string
+	max_frames        int
+}
+
+func compileStackTraces(cfgFile *Config) (stackTraceOptions, error) {
+	options := stackTraceOptions{
+		disabled:          cfgFile.StackTraces.Disabled,
+		keep_line_numbers: cfgFile.StackTraces.KeepLineNumbers,
+		keep_lambdas:      cfgFile.StackTraces.KeepLambdas,
+		app_packages:      cfgFile.StackTraces.AppPackages,
+		max_frames:        cfgFile.StackTraces.MaxFrames,
+	}
+	if options.max_frames < 0 {
+		return options, errors.New("StackTraces.MaxFrames must be positive")
+	}
+	if options.max_frames == 0 {
+		options.max_frames = defaultTraceFrames
+	}
+	return options, nil
+}
+func (trace StackTrace) Exception() string {
+	return trace.Chain[0].Class
+}
+
+// signature is the known signature a stack trace matches, MarkKnown prefills it in the signatures form
+func (trace StackTrace) signature() KnownSignature {
+	return KnownSignature{Issue: trace.Exception(), Stack: trace.Hash}
+}
+func (trace StackTrace) MarkKnown() string {
+	query := url.Values{}
+	query.Set("issue", trace.Exception())
+	query.Set("stack", trace.Hash)
+	return "/signatures?" + query.Encode()
+}
+
+// RootCause is the last exception of the chain, usually the one to fix
+func (trace StackTrace) RootCause() TraceException {
+	return trace.Chain[len(trace.Chain)-1]
+}
+
+// extractStackTraces reads the traces of the log and groups the identical ones, the most frequent first
+func extractStackTraces(matchers *matcherSet, log *logLines) []StackTrace {
+	traces := []StackTrace{}
+	if matchers == nil || matchers.stack_traces.disabled {
+		return traces
+	}
+	by_hash := make(map[string]int)
+	//The lines of a trace, such as its "Caused by:", do not start another one
+	claimed := make(map[int]bool)
+	for index := 0; index < len(log.lines); index++ {
+		if claimed[index] || !mayStartTrace(log.lines[index]) {
+			continue
+		}
+		chain, lines := readStackTrace(matchers, log, index)
+		if chain == nil {
+			continue
+		}
+		hash := matchers.stack_traces.normalize(chain)
+		position, ok := by_hash[hash]
+		if !ok {
+			position = len(traces)
+			by_hash[hash] = position
+			example := make([]string, 0, len(lines))
+			for _, line := range lines {
+				if len(example) == maxTraceExampleLines {
+					break
+				}
+				example = append(example, log.lines[line])
+			}
+			traces = append(traces, StackTrace{Hash: hash, Chain: chain, Example: example, End: lines[len(lines)-1] + 1})
+		}
+		trace := &traces[position]
+		trace.Count++
+		trace.Lines = append(trace.Lines, index+1)
+		if matchers.timestamp != nil {
+			if timestamp := matchers.timestamp.FindString(log.lines[index]); timestamp != "" {
+				if trace.First == "" {
+					trace.First = timestamp
+				}
+				trace.Last = timestamp
+			}
+		}
+		for _, line := range lines {
+			claimed[line] = true
+		}
+	}
+	sort.SliceStable(traces, func(i, j int) bool {
+		if traces[i].Count != traces[j].Count {
+			return traces[i].Count > traces[j].Count
+		}
+		return traces[i].Exception() < traces[j].Exception()
+	})
+	return traces
+}
+func mayStartTrace(line string) bool {
+	for _, keyword := range traceKeywords {
+		if strings.Contains(line, keyword) {
+			return !traceFrame.MatchString(line) && traceHeader.MatchString(line)
+		}
+	}
+	return false
+}
+
+// readStackTrace returns the exceptions of the trace that starts at index and the indexes of its lines,
+// nil when the exception has no frame. With a Pid pattern, the lines of other processes are skipped
+func readStackTrace(matchers *matcherSet, log *logLines, index int) ([]TraceException, []int) {
+	header := traceHeader.FindStringSubmatch(log.lines[index])
+	chain := []TraceException{{Class: header[1], Message: header[2]}}
+	lines := []int{index}
+	pid := linePid(matchers, log.lines[index])
+	skipped, message_lines := 0, 0
+	for line := index + 1; line < len(log.lines) && skipped <= maxTraceInterleaved; line++ {
+		content := log.lines[line]
+		//Lines without a pid, such as the frames of a plain Java log, continue the trace
+		if line_pid := linePid(matchers, content); pid != "" && line_pid != "" && line_pid != pid {
+			skipped++
+			continue
+		}
+		current := &chain[len(chain)-1]
+		if frame := traceFrame.FindStringSubmatch(content); frame != nil {
+			current.Frames = append(current.Frames, frame[1]+"("+frame[2]+")")
+		} else if cause := traceCause.FindStringIndex(content); cause != nil {
+			rest := content[cause[1]:]
+			if header := traceHeader.FindStringSubmatch(rest); header != nil && strings.HasPrefix(rest, header[1]) {
+				//Suppressed exceptions are part of the trace but not of its chain
+				if !strings.HasPrefix(content[cause[0]:], "Suppressed") {
+					chain = append(chain, TraceException{Class: header[1], Message: header[2]})
+				}
+			}
+		} else if !traceMore.MatchString(content) {
+			if len(chain) > 1 || len(chain[0].Frames) > 0 || message_lines == maxTraceMessageLines {
+				break
+			}
+			message_lines++
+		}
+		lines = append(lines, line)
+		skipped = 0
+	}
+	if len(chain[0].Frames) == 0 {
+		return nil, nil
+	}
+	return chain, lines
+}
+
+// linePid reads the pid like the other fields, the whole match when the Pid pattern has no group
+func linePid(matchers *matcherSet, line string) string {
+	if matchers.pid == nil {
+		return ""
+	}
+	return captureField(matchers.pid, line)
+}
+
+// normalize replaces the frames of every exception by its top frames, its app frames when AppPackages are set,
+// and returns the hash of the classes and kept frames. Messages are not hashed, they often hold ids
+func (options stackTraceOptions) normalize(chain []TraceException) string {
+	signature := sha1.New()
+	for index, exception := range chain {
+		frames := make([]string, len(exception.Frames))
+		for position, frame := range exception.Frames {
+			frames[position] = options.normalizeFrame(frame)
+		}
+		kept := []string{}
+		for _, frame := range frames {
+			if len(kept) == options.max_frames {
+				break
+			}
+			if options.isAppFrame(frame) {
+				kept = append(kept, frame)
+			}
+		}
+		if len(kept) == 0 {
+			for _, frame := range frames {
+				if len(kept) == options.max_frames {
+					break
+				}
+				kept = append(kept, frame)
+			}
+		}
+		chain[index].Frames = kept
+		signature.Write([]byte(exception.Class + "\n" + strings.Join(kept, "\n") + "\n\n"))
+	}
+	return hex.EncodeToString(signature.Sum(nil))[:16]
+}
+func (options stackTraceOptions) normalizeFrame(frame string) string {
+	if !options.keep_line_numbers {
+		frame = traceLine.ReplaceAllString(frame, ")")
+	}
+	if !options.keep_lambdas {
+		frame = traceLambda.ReplaceAllStringFunc(frame, func(lambda string) string {
+			if strings.HasPrefix(lambda, "$$") {
+				return "$$Lambda"
+			}
+			if strings.HasPrefix(lambda, "$") {
+				return "$lambda"
+			}
+			return "lambda"
+		})
+	}
+	return frame
+}
+func (options stackTraceOptions) isAppFrame(frame string) bool {
+	if len(options.app_packages) == 0 {
+		return false
+	}
+	for _, app_package := range options.app_packages {
+		if strings.HasPrefix(frame, app_package+".") {
+			return true
+		}
+	}
+	return false
+}
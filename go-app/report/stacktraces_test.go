@@ -0,0 +1,157 @@
+package report
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const traceLogcat = `10-19 14:02:11.470  4312  4312 I ActivityManager: Start proc 4312:com.example.app/u0a245
+10-19 14:02:11.482  4312  4312 E AndroidRuntime: FATAL EXCEPTION: main
+10-19 14:02:11.482  4312  4312 E AndroidRuntime: Process: com.example.app, PID: 4312
+10-19 14:02:11.482  4312  4312 E AndroidRuntime: java.lang.RuntimeException: Unable to start activity ComponentInfo{com.example.app/com.example.app.MainActivity}: java.lang.IllegalStateException: Repository not ready
+10-19 14:02:11.482  4312  4312 E AndroidRuntime: 	at android.app.ActivityThread.performLaunchActivity(ActivityThread.java:3449)
+10-19 14:02:11.482  4312  4312 E AndroidRuntime: 	at android.app.ActivityThread.handleLaunchActivity(ActivityThread.java:3601)
+10-19 14:02:11.483  1021  1044 I ActivityManager: Showing crash dialog for package com.example.app u0
+10-19 14:02:11.483  4312  4312 E AndroidRuntime: 	at android.os.Handler.dispatchMessage(Handler.java:106)
+10-19 14:02:11.483  4312  4312 E AndroidRuntime: Caused by: java.lang.IllegalStateException: Repository not ready
+10-19 14:02:11.483  4312  4312 E AndroidRuntime: 	at com.example.app.data.Repository.load(Repository.kt:88)
+10-19 14:02:11.483  4312  4312 E AndroidRuntime: 	at com.example.app.MainActivity.lambda$onCreate$0(MainActivity.kt:41)
+10-19 14:02:11.483  4312  4312 E AndroidRuntime: 	at com.example.app.MainActivity$$ExternalSyntheticLambda2.run(Unknown Source:2)
+10-19 14:02:11.483  4312  4312 E AndroidRuntime: 	at com.example.app.MainActivity.onCreate(MainActivity.kt:40)
+10-19 14:02:11.483  4312  4312 E AndroidRuntime: 	... 12 more
+10-19 14:02:11.490  4312  4312 I Process : Sending signal. PID: 4312 SIG: 9
+10-19 14:05:00.100  2200  2250 W System.err: java.io.IOException: write failed: EPIPE (Broken pipe)
+10-19 14:05:00.100  2200  2250 W System.err: 	at libcore.io.IoBridge.write(IoBridge.java:540)
+10-19 14:05:00.100  2200  2250 W System.err: 	at java.io.FileOutputStream.write(FileOutputStream.java:398)
+10-19 14:05:00.100  2200  2250 W System.err: 	Suppressed: java.io.IOException: close failed
+10-19 14:05:00.100  2200  2250 W System.err: 		at libcore.io.IoBridge.closeAndSignalBlockedThreads(IoBridge.java:211)
+10-19 14:05:00.100  2200  2250 W System.err: 		... 4 more
+10-19 14:05:00.101  2200  2250 W System.err: Caused by: android.system.ErrnoException: write failed: EPIPE (Broken pipe)
+10-19 14:05:00.101  2200  2250 W System.err: 	at libcore.io.Linux.writeBytes(Native Method)
+10-19 14:05:00.101  2200  2250 W System.err: 	... 6 more
+10-19 14:07:40.915  4500  4500 E AndroidRuntime: FATAL EXCEPTION: main
+10-19 14:07:40.915  4500  4500 E AndroidRuntime: Process: com.example.app, PID: 4500
+10-19 14:07:40.915  4500  4500 E AndroidRuntime: java.lang.RuntimeException: Unable to start activity ComponentInfo{com.example.app/com.example.app.MainActivity}: java.lang.IllegalStateException: Repository not ready
+10-19 14:07:40.915  4500  4500 E AndroidRuntime: 	at android.app.ActivityThread.performLaunchActivity(ActivityThread.java:3449)
+10-19 14:07:40.915  4500  4500 E AndroidRuntime: 	at android.app.ActivityThread.handleLaunchActivity(ActivityThread.java:3601)
+10-19 14:07:40.915  4500  4500 E AndroidRuntime: 	at android.os.Handler.dispatchMessage(Handler.java:106)
+10-19 14:07:40.916  4500  4500 E AndroidRuntime: Caused by: java.lang.IllegalStateException: Repository not ready
+10-19 14:07:40.916  4500  4500 E AndroidRuntime: 	at com.example.app.data.Repository.load(Repository.kt:90)
+10-19 14:07:40.916  4500  4500 E AndroidRuntime: 	at com.example.app.MainActivity.lambda$onCreate$1(MainActivity.kt:43)
+10-19 14:07:40.916  4500  4500 E AndroidRuntime: 	at com.example.app.MainActivity$$ExternalSyntheticLambda3.run(Unknown Source:2)
+10-19 14:07:40.916  4500  4500 E AndroidRuntime: 	at com.example.app.MainActivity.onCreate(MainActivity.kt:40)
+10-19 14:07:40.916  4500  4500 E AndroidRuntime: 	... 12 more`
+
+const traceConfig = `
+IssuesGeneralFields:
+  Timestamp: '\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{3}'
+  Pid: '^\S+ \S+\s+(\d+)'
+`
+
+func TestExtractStackTraces(t *testing.T) {
+	cfg := testConfig(t, traceConfig+`
+StackTraces:
+  AppPackages: [com.example.app]
+  MaxFrames: 2
+`)
+	traces := extractStackTraces(cfg.matchers, splitLines("traces.txt", traceLogcat))
+	if len(traces) != 2 {
+		t.Fatalf("%d traces: %+v", len(traces), traces)
+	}
+	crash := traces[0]
+	expected := []TraceException{
+		{"java.lang.RuntimeException", "Unable to start activity ComponentInfo{com.example.app/com.example.app.MainActivity}: java.lang.IllegalStateException: Repository not ready", []string{"android.app.ActivityThread.performLaunchActivity(ActivityThread.java)", "android.app.ActivityThread.handleLaunchActivity(ActivityThread.java)"}},
+		{"java.lang.IllegalStateException", "Repository not ready", []string{"com.example.app.data.Repository.load(Repository.kt)", "com.example.app.MainActivity.lambda(MainActivity.kt)"}},
+	}
+	if !reflect.DeepEqual(crash.Chain, expected) {
+		t.Errorf("chain %+v", crash.Chain)
+	}
+	if crash.Count != 2 || !reflect.DeepEqual(crash.Lines, []int{4, 27}) || crash.First != "10-19 14:02:11.482" || crash.Last != "10-19 14:07:40.915" || crash.End != 14 {
+		t.Errorf("crash %d at %v from %s to %s, ends on %d", crash.Count, crash.Lines, crash.First, crash.Last, crash.End)
+	}
+	//The line of another process is not part of the trace, its "... 12 more" is
+	example := strings.Join(crash.Example, "\n")
+	if len(crash.Example) != 10 || strings.Contains(example, "crash dialog") || !strings.HasSuffix(example, "... 12 more") {
+		t.Errorf("example %q", crash.Example)
+	}
+	pipe := traces[1]
+	if pipe.Count != 1 || len(pipe.Chain) != 2 || pipe.RootCause().Class != "android.system.ErrnoException" || !reflect.DeepEqual(pipe.RootCause().Frames, []string{"libcore.io.Linux.writeBytes(Native Method)"}) {
+		t.Errorf("suppressed exceptions are not causes: %+v", pipe.Chain)
+	}
+	if len(pipe.Example) != 9 {
+		t.Errorf("example %q", pipe.Example)
+	}
+}
+
+// The two crashes differ only by their line numbers and lambdas
+func TestStackTraceKeptDetails(t *testing.T) {
+	for _, keep := range []string{"", "KeepLineNumbers: true", "KeepLambdas: true"} {
+		cfg := testConfig(t, traceConfig+"StackTraces:\n  "+keep+"\n")
+		traces := extractStackTraces(cfg.matchers, splitLines("traces.txt", traceLogcat))
+		if expected := map[bool]int{true: 2, false: 3}[keep == ""]; len(traces) != expected {
+			t.Errorf("%q: %d traces, expected %d", keep, len(traces), expected)
+		}
+	}
+}
+
+func TestNormalizeFrame(t *testing.T) {
+	tests := []struct {
+		frame      string
+		normalized string
+	}{
+		{"android.os.Handler.dispatchMessage(Handler.java:106)", "android.os.Handler.dispatchMessage(Handler.java)"},
+		{"com.example.app.MainActivity$$ExternalSyntheticLambda2.run(Unknown Source:2)", "com.example.app.MainActivity$$Lambda.run(Unknown Source)"},
+		{"com.example.app.Feed$$Lambda$42/0x0000000801234.accept(Unknown Source)", "com.example.app.Feed$$Lambda.accept(Unknown Source)"},
+		{"com.example.app.MainActivity.lambda$onCreate$0(MainActivity.kt:41)", "com.example.app.MainActivity.lambda(MainActivity.kt)"},
+		{"com.example.app.Sync$lambda-3$lambda-2.invoke(Sync.kt:12)", "com.example.app.Sync$lambda.invoke(Sync.kt)"},
+		{"libcore.io.Linux.writeBytes(Native Method)", "libcore.io.Linux.writeBytes(Native Method)"},
+	}
+	for _, test := range tests {
+		if normalized := (stackTraceOptions{}).normalizeFrame(test.frame); normalized != test.normalized {
+			t.Errorf("%s normalized to %s, expected %s", test.frame, normalized, test.normalized)
+		}
+	}
+	kept := stackTraceOptions{keep_line_numbers: true, keep_lambdas: true}
+	if frame := tests[3].frame; kept.normalizeFrame(frame) != frame {
+		t.Errorf("%s changed to %s", frame, kept.normalizeFrame(frame))
+	}
+}
+
+// Up to maxTraceInterleaved lines of other processes may come between two frames of a trace
+func TestStackTraceInterleaveLimit(t *testing.T) {
+	cfg := testConfig(t, traceConfig)
+	for _, interleaved := range []int{maxTraceInterleaved, maxTraceInterleaved + 1} {
+		lines := []string{
+			"10-19 14:02:11.482  4312  4312 E AndroidRuntime: java.lang.IllegalStateException: Repository not ready",
+			"10-19 14:02:11.482  4312  4312 E AndroidRuntime: 	at com.example.app.data.Repository.load(Repository.kt:88)",
+		}
+		for i := 0; i < interleaved; i++ {
+			lines = append(lines, fmt.Sprintf("10-19 14:02:11.483  1021  1044 I ActivityManager: line %d", i))
+		}
+		lines = append(lines, "10-19 14:02:11.484  4312  4312 E AndroidRuntime: 	at com.example.app.MainActivity.onCreate(MainActivity.kt:40)")
+		traces := extractStackTraces(cfg.matchers, splitLines("traces.txt", strings.Join(lines, "\n")))
+		frames := 2
+		if interleaved > maxTraceInterleaved {
+			frames = 1
+		}
+		if len(traces) != 1 || len(traces[0].Chain[0].Frames) != frames {
+			t.Errorf("%d interleaved lines: %+v, expected %d frames", interleaved, traces, frames)
+		}
+	}
+}
+
+// A Pid pattern without a group still keeps the frames of other processes out of a trace
+func TestStackTracePidPatternWithoutGroup(t *testing.T) {
+	cfg := testConfig(t, `
+IssuesGeneralFields:
+  Pid: '\s\d+\s'
+`)
+	lines := strings.Split(traceLogcat, "\n")[3:14]
+	lines[3] = "10-19 14:02:11.483  1021  1044 E Other: 	at com.other.Thing.run(Thing.java:1)"
+	traces := extractStackTraces(cfg.matchers, splitLines("traces.txt", strings.Join(lines, "\n")))
+	if len(traces) != 1 || len(traces[0].Example) != 10 || strings.Contains(strings.Join(traces[0].Chain[0].Frames, ","), "com.other") {
+		t.Fatalf("%+v", traces)
+	}
+}
@@ -0,0 +1,69 @@
+package report
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+type AnalysisStore struct {
+	mutex    sync.Mutex
+	size     int
+	order    []string
+	analyses map[string]*FullDetails
+}
+
+func NewAnalysisStore(size int) *AnalysisStore {
+	return &AnalysisStore{size: size, analyses: make(map[string]*FullDetails)}
+}
+func newAnalysisId() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// Add keeps a copy of the analysis, dropping the oldest one once the store is full
+// The analysis must come from analyseContent, the stored reports are rendered with its Config
+func (store *AnalysisStore) Add(fullLogDetails FullDetails) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	id := fullLogDetails.Analysis_details.Id
+	if _, ok := store.analyses[id]; !ok {
+		store.order = append(store.order, id)
+	}
+	store.analyses[id] = &fullLogDetails
+	for len(store.order) > store.size {
+		delete(store.analyses, store.order[0])
+		store.order = store.order[1:]
+	}
+}
+func (store *AnalysisStore) Get(id string) (*FullDetails, bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	fullLogDetails, ok := store.analyses[id]
+	return fullLogDetails, ok
+}
+
+// Update replaces a stored analysis by a copy changed by update, so that the pages reading the
+// former one keep an unchanged analysis. It returns false when the analysis is no longer stored
+func (store *AnalysisStore) Update(id string, update func(*FullDetails)) bool {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	fullLogDetails, ok := store.analyses[id]
+	if !ok {
+		return false
+	}
+	updated := *fullLogDetails
+	update(&updated)
+	store.analyses[id] = &updated
+	return true
+}
+
+// List returns the stored analyses, most recent first
+func (store *AnalysisStore) List() []AnalysisDetails {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	analyses := make([]AnalysisDetails, 0, len(store.order))
+	for index := len(store.order) - 1; index >= 0; index-- {
+		analyses = append(analyses, store.analyses[store.order[index]].Analysis_details)
+	}
+	return analyses
+}
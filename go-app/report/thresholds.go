@@ -0,0 +1,163 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	statusTriggered      = "Triggered"
+	statusBelowThreshold = "Below threshold"
+	statusNotEvaluable   = "Not evaluable"
+	statusNotFound       = "Not found"
+)
+
+// threshold holds the conditions an issue must meet to be reported as triggered
+type threshold struct {
+	min_count    int
+	max_rate     float64
+	rate_unit    time.Duration
+	window_count int
+	window       time.Duration
+}
+
+func (limit threshold) isSet() bool {
+	return limit.min_count > 0 || limit.max_rate > 0 || limit.window > 0
+}
+func compileThreshold(issue Issue) (threshold, error) {
+	limit := threshold{min_count: issue.min_count}
+	if issue.min_count < 0 {
+		return limit, errors.New("min_count must be positive")
+	}
+	if issue.max_rate != "" {
+		rate, unit, err := parseRate(issue.max_rate)
+		if err != nil {
+			return limit, fmt.Errorf("max_rate: %v", err)
+		}
+		limit.max_rate, limit.rate_unit = rate, unit
+	}
+	if issue.rate_window != "" {
+		count, window, err := parseRate(issue.rate_window)
+		if err != nil {
+			return limit, fmt.Errorf("rate_window: %v", err)
+		}
+		limit.window_count, limit.window = int(count), window
+	}
+	return limit, nil
+}
+
+// parseRate reads rates such as "50/1m", "50/min" or "2/s", a bare number is per minute
+func parseRate(rate string) (float64, time.Duration, error) {
+	parts := strings.SplitN(strings.Replace(rate, " ", "", -1), "/", 2)
+	count, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || count < 0 {
+		return 0, 0, fmt.Errorf("invalid count in %q", rate)
+	}
+	if len(parts) == 1 {
+		return count, time.Minute, nil
+	}
+	unit := parts[1]
+	switch unit {
+	case "s", "sec", "second":
+		return count, time.Second, nil
+	case "m", "min", "minute":
+		return count, time.Minute, nil
+	case "h", "hour":
+		return count, time.Hour, nil
+	}
+	duration, err := time.ParseDuration(unit)
+	if err != nil || duration <= 0 {
+		return 0, 0, fmt.Errorf("invalid duration in %q", rate)
+	}
+	return count, duration, nil
+}
+
+// evaluateThresholds sets the Status of every issue and, for issues with conditions, the measured values
+func evaluateThresholds(cfgFile *Config, fContent string, issues_map map[string]map[string]string, issues_times map[string][]time.Time, headerMap map[string]bool) {
+	var span time.Duration
+	has_span := false
+	if cfgFile.matchers.timestamp != nil {
+		start, end, ok := logTimeSpan(cfgFile.matchers.timestamp, fContent)
+		span, has_span = end.Sub(start), ok
+	}
+	for issue_name, issue_map := range issues_map {
+		count, _ := strconv.Atoi(issue_map["Number"])
+		limit := cfgFile.matchers.issues[issue_name].threshold
+		if count == 0 {
+			issue_map["Status"] = statusNotFound
+			continue
+		}
+		if !limit.isSet() {
+			issue_map["Status"] = statusTriggered
+			continue
+		}
+		headerMap["Threshold"] = true
+		//A rate or a peak that could not be measured is neither triggered nor below its threshold
+		triggered, evaluable := true, true
+		measures := []string{}
+		if limit.min_count > 0 {
+			triggered = triggered && count >= limit.min_count
+			measures = append(measures, fmt.Sprintf("count %d (min %d)", count, limit.min_count))
+		}
+		if limit.max_rate > 0 {
+			if has_span && span > 0 {
+				rate := float64(count) * float64(limit.rate_unit) / float64(span)
+				triggered = triggered && rate > limit.max_rate
+				measures = append(measures, fmt.Sprintf("rate %.1f/%v (max %.1f)", rate, limit.rate_unit, limit.max_rate))
+			} else {
+				evaluable = false
+				measures = append(measures, fmt.Sprintf("rate not measured, the log has no time span (max %.1f)", limit.max_rate))
+			}
+		}
+		if limit.window > 0 {
+			if times := issues_times[issue_name]; len(times) > 0 {
+				peak := peakInWindow(times, limit.window)
+				triggered = triggered && peak > limit.window_count
+				measures = append(measures, fmt.Sprintf("peak %d in %v (max %d)", peak, limit.window, limit.window_count))
+			} else {
+				evaluable = false
+				measures = append(measures, fmt.Sprintf("peak not measured, the matches have no timestamp (max %d)", limit.window_count))
+			}
+		}
+		issue_map["Threshold"] = strings.Join(measures, ", ")
+		if !triggered {
+			issue_map["Status"] = statusBelowThreshold
+		} else if !evaluable {
+			issue_map["Status"] = statusNotEvaluable
+		} else {
+			issue_map["Status"] = statusTriggered
+		}
+	}
+}
+
+// peakInWindow returns the highest number of occurrences within any window of the given duration
+func peakInWindow(times []time.Time, window time.Duration) int {
+	sorted := make([]time.Time, len(times))
+	copy(sorted, times)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+	peak, start := 0, 0
+	for end := range sorted {
+		for sorted[end].Sub(sorted[start]) >= window {
+			start++
+		}
+		if end-start+1 > peak {
+			peak = end - start + 1
+		}
+	}
+	return peak
+}
+func statusRank(status string) int {
+	switch status {
+	case statusTriggered:
+		return 3
+	case statusNotEvaluable:
+		return 2
+	case statusBelowThreshold:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,124 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		rate  string
+		count float64
+		unit  time.Duration
+	}{
+		{"50/1m", 50, time.Minute},
+		{"50/min", 50, time.Minute},
+		{"2/s", 2, time.Second},
+		{"1.5 / hour", 1.5, time.Hour},
+		{"3/10m", 3, 10 * time.Minute},
+		{"10", 10, time.Minute},
+	}
+	for _, test := range tests {
+		count, unit, err := parseRate(test.rate)
+		if err != nil || count != test.count || unit != test.unit {
+			t.Errorf("%q: %v/%v %v, expected %v/%v", test.rate, count, unit, err, test.count, test.unit)
+		}
+	}
+	for _, rate := range []string{"", "many/m", "-1/m", "5/0s", "5/fortnight"} {
+		if _, _, err := parseRate(rate); err == nil {
+			t.Errorf("%q: no error", rate)
+		}
+	}
+}
+
+func TestPeakInWindow(t *testing.T) {
+	at := func(seconds ...int) []time.Time {
+		times := []time.Time{}
+		for _, second := range seconds {
+			times = append(times, time.Date(0, 10, 19, 14, 0, second, 0, time.UTC))
+		}
+		return times
+	}
+	tests := []struct {
+		times  []time.Time
+		window time.Duration
+		peak   int
+	}{
+		{nil, time.Minute, 0},
+		{at(0), time.Second, 1},
+		{at(30, 0, 9, 5, 50), 10 * time.Second, 3},
+		//A match exactly one window later starts a new window
+		{at(0, 10, 20), 10 * time.Second, 1},
+		{at(0, 1, 2, 40, 41), time.Minute, 5},
+	}
+	for _, test := range tests {
+		if peak := peakInWindow(test.times, test.window); peak != test.peak {
+			t.Errorf("%v in %v: peak %d, expected %d", test.times, test.window, peak, test.peak)
+		}
+	}
+}
+
+// Rates and peaks that cannot be measured are not reported as below their threshold
+func TestEvaluateThresholds(t *testing.T) {
+	cfg := testConfig(t, `
+IssuesGeneralFields:
+  Timestamp: '\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{3}'
+Issues:
+  Count:
+    contains: FATAL
+    min_count: 3
+  Rate:
+    contains: FATAL
+    max_rate: 1/m
+  Window:
+    contains: FATAL
+    rate_window: 1/10s
+  Both:
+    contains: FATAL
+    min_count: 3
+    max_rate: 1/m
+`)
+	timed := "10-19 14:00:00.000 E FATAL\n10-19 14:00:05.000 E FATAL\n10-19 14:01:00.000 I done"
+	tests := []struct {
+		log      string
+		times    []time.Time
+		statuses map[string]string
+	}{
+		{timed, []time.Time{time.Date(0, 10, 19, 14, 0, 0, 0, time.UTC), time.Date(0, 10, 19, 14, 0, 5, 0, time.UTC)}, map[string]string{
+			"Count":  statusBelowThreshold,
+			"Rate":   statusTriggered,
+			"Window": statusTriggered,
+			"Both":   statusBelowThreshold,
+		}},
+		{"E FATAL\nE FATAL\nI done", nil, map[string]string{
+			"Count":  statusBelowThreshold,
+			"Rate":   statusNotEvaluable,
+			"Window": statusNotEvaluable,
+			"Both":   statusBelowThreshold,
+		}},
+	}
+	for _, test := range tests {
+		issues_map := make(map[string]map[string]string)
+		issues_times := make(map[string][]time.Time)
+		for issue_name := range cfg.Issues {
+			issues_map[issue_name] = map[string]string{"Number": "2"}
+			issues_times[issue_name] = test.times
+		}
+		evaluateThresholds(cfg, test.log, issues_map, issues_times, make(map[string]bool))
+		for issue_name, status := range test.statuses {
+			if issues_map[issue_name]["Status"] != status {
+				t.Errorf("%q, %s: %s (%s), expected %s", test.log, issue_name, issues_map[issue_name]["Status"], issues_map[issue_name]["Threshold"], status)
+			}
+		}
+	}
+}
+
+func TestCompileConfigRatesNeedTimestamps(t *testing.T) {
+	for _, limit := range []string{"max_rate: 10", "rate_window: 3/10m"} {
+		err := readConfig([]byte("Issues:\n  Rated:\n    contains: FATAL\n    "+limit+"\n"), &Config{})
+		if err == nil || !strings.Contains(err.Error(), "Issues.Rated: max_rate and rate_window need IssuesGeneralFields.Timestamp") {
+			t.Errorf("%s: %v", limit, err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package report
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+const (
+	//Markers closer than one slot are drawn once, with a count
+	timeAxisSlots = 4000
+	//A line without a timestamp takes the time of the closest line above it that has one
+	maxTimestampLookback = 50
+)
+
+// TimeAxis places the important events, the issue occurrences and the process lifecycle on parallel tracks
+type TimeAxis struct {
+	Start   time.Time
+	End     time.Time
+	LogSize int
+	Tracks  []TimeTrack
+}
+type TimeTrack struct {
+	Name    string
+	Kind    string
+	Markers []TimeMarker
+}
+
+// TimeMarker is one or more lines at the same place of the axis, Line is the 0-based index of the first one
+type TimeMarker struct {
+	Line  int
+	Time  string
+	Label string
+	Class string
+	Count int
+	Left  float64
+}
+type timeAxisPoint struct {
+	index int
+	label string
+	class string
+}
+
+func (axis TimeAxis) StartMs() int64 {
+	return axis.Start.UnixNano() / int64(time.Millisecond)
+}
+func (axis TimeAxis) EndMs() int64 {
+	return axis.End.UnixNano() / int64(time.Millisecond)
+}
+
+// buildTimeAxis needs the Timestamp pattern of the config, events maps line indexes to their events
+func buildTimeAxis(fullLogDetails *FullDetails, matchers *matcherSet, events map[int][]EventHit) (TimeAxis, error) {
+	axis := TimeAxis{}
+	if matchers == nil || matchers.timestamp == nil {
+		return axis, errors.New("The time axis needs a Timestamp pattern in the config")
+	}
+	log := splitLines(fullLogDetails.Analysis_details.FileName, fullLogDetails.Analysis_details.RawLog)
+	axis.LogSize = len(log.lines)
+	start, end, ok := logTimeSpan(matchers.timestamp, fullLogDetails.Analysis_details.RawLog)
+	if !ok {
+		return axis, errors.New("The log has no timestamps")
+	}
+	axis.Start, axis.End = start, end
+	event_points := make(map[string][]timeAxisPoint)
+	for index, hits := range events {
+		for _, hit := range hits {
+			event_points[hit.trackName()] = append(event_points[hit.trackName()], timeAxisPoint{index, hit.String(), "event severity_" + hit.Severity})
+		}
+	}
+	event_names := make([]string, 0, len(event_points))
+	for event := range event_points {
+		event_names = append(event_names, event)
+	}
+	sort.Strings(event_names)
+	for _, event := range event_names {
+		axis.addTrack(matchers, log, event, "event", event_points[event])
+	}
+	for _, issue := range fullLogDetails.Analysis_details.OrderedIssues {
+		points := []timeAxisPoint{}
+		for _, ref := range issueRefs(fullLogDetails, issue) {
+			points = append(points, timeAxisPoint{ref.Line - 1, issue, "issue"})
+		}
+		axis.addTrack(matchers, log, issue, "issue", points)
+	}
+	for _, summary := range fullLogDetails.Analysis_details.Timeline.Processes {
+		points := []timeAxisPoint{}
+		for _, life := range summary.Lives {
+			if life.StartLine > 0 {
+				points = append(points, timeAxisPoint{life.StartLine - 1, life.Pid + " started", "start"})
+			}
+			if life.EndLine > 0 {
+				class := "death"
+				if life.Crashed {
+					class = "crash"
+				} else if life.Killed {
+					class = "kill"
+				}
+				points = append(points, timeAxisPoint{life.EndLine - 1, life.Pid + " " + life.Ending(), class})
+			}
+		}
+		axis.addTrack(matchers, log, summary.Process, "lifecycle", points)
+	}
+	return axis, nil
+}
+
+// addTrack places the points in line order, the points of the same slot and class are merged
+func (axis *TimeAxis) addTrack(matchers *matcherSet, log *logLines, name string, kind string, points []timeAxisPoint) {
+	if len(points) == 0 {
+		return
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].index < points[j].index })
+	track := TimeTrack{Name: name, Kind: kind}
+	span := axis.End.Sub(axis.Start)
+	last_slot := -1
+	for _, point := range points {
+		t, ok := timeOfLine(matchers, log, point.index)
+		if !ok {
+			continue
+		}
+		left := 0.0
+		if span > 0 {
+			left = float64(t.Sub(axis.Start)) * 100 / float64(span)
+		}
+		slot := int(left * timeAxisSlots / 100)
+		if count := len(track.Markers); count > 0 && slot == last_slot && track.Markers[count-1].Class == point.class {
+			track.Markers[count-1].Count++
+			continue
+		}
+		last_slot = slot
+		track.Markers = append(track.Markers, TimeMarker{
+			Line:  point.index,
+			Time:  t.Format("15:04:05.000"),
+			Label: point.label,
+			Class: point.class,
+			Count: 1,
+			Left:  left,
+		})
+	}
+	if len(track.Markers) > 0 {
+		axis.Tracks = append(axis.Tracks, track)
+	}
+}
+func (marker TimeMarker) Number() int {
+	return marker.Line + 1
+}
+func timeOfLine(matchers *matcherSet, log *logLines, index int) (time.Time, bool) {
+	for line := index; line >= 0 && line < len(log.lines) && index-line <= maxTimestampLookback; line-- {
+		if t, ok := parseTimestamp(matchers.timestamp.FindString(log.lines[line])); ok {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,44 @@
+package report
+
+import (
+	"strings"
+	"testing"
+)
+
+// Markers are placed by the time of their line, a line without a timestamp takes the time of the line above
+func TestBuildTimeAxisTracks(t *testing.T) {
+	cfg := testConfig(t, "IssuesGeneralFields:\n  Timestamp: '\\d{2}-\\d{2} \\d{2}:\\d{2}:\\d{2}'\n"+eventsConfig)
+	fullLogDetails := &FullDetails{NonGroupedIssues: map[string][]LineRef{"Crash": {{Line: 4}}}}
+	fullLogDetails.Analysis_details.OrderedIssues = []string{"Crash"}
+	fullLogDetails.Analysis_details.RawLog = strings.Join([]string{
+		"10-19 14:00:00.000 I SystemServer: boot completed",
+		"10-19 14:00:10.000 I Wifi: connected to home on 5GHz",
+		"    retried: connected to work on 2GHz",
+		"10-19 14:00:20.000 E AndroidRuntime: FATAL EXCEPTION: main",
+	}, "\n")
+	events := make(map[int][]EventHit)
+	getImportantEvents(cfg, fullLogDetails.Analysis_details.RawLog, events)
+	axis, err := buildTimeAxis(fullLogDetails, cfg.matchers, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracks := make(map[string]TimeTrack)
+	names := []string{}
+	for _, track := range axis.Tracks {
+		tracks[track.Name] = track
+		names = append(names, track.Kind+" "+track.Name)
+	}
+	if strings.Join(names, ", ") != "event Boot, event app: Crash, event wifi: Connect, issue Crash" || axis.LogSize != 4 {
+		t.Fatalf("tracks %v, %d lines", names, axis.LogSize)
+	}
+	connect := tracks["wifi: Connect"].Markers
+	if len(connect) != 1 || connect[0].Count != 2 || connect[0].Left != 50 || connect[0].Time != "14:00:10.000" || connect[0].Number() != 2 || connect[0].Label != "Connect ssid=home band=5GHz" {
+		t.Errorf("connect markers %+v", connect)
+	}
+	if crash := tracks["Crash"].Markers; len(crash) != 1 || crash[0].Left != 100 || crash[0].Class != "issue" {
+		t.Errorf("issue markers %+v", crash)
+	}
+	if _, err := buildTimeAxis(fullLogDetails, testConfig(t, eventsConfig).matchers, events); err == nil {
+		t.Error("a time axis without a Timestamp pattern")
+	}
+}
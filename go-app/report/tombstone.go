@@ -0,0 +1,129 @@
+package report
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	maxTombstoneFrames = 64
+	//Lines between the "*** ***" header and the backtrace, the registers and the build lines are in between
+	maxTombstoneHeaderLines = 100
+)
+
+var (
+	tombstoneStart     = regexp.MustCompile(`\*\*\* \*\*\* \*\*\* \*\*\* \*\*\*`)
+	tombstoneProcess   = regexp.MustCompile(`\bpid: (\d+), tid: (\d+), name: (.*?)\s+>>> (.*?) <<<`)
+	tombstoneSignal    = regexp.MustCompile(`\bsignal (\d+) \((\w+)\), code (-?\d+) \(([^)]*)\)(?:, fault addr (\S+))?`)
+	tombstoneAbort     = regexp.MustCompile(`\bAbort message: '(.*)'?\s*$`)
+	tombstoneCause     = regexp.MustCompile(`\bCause: (.*?)\s*$`)
+	tombstoneBuild     = regexp.MustCompile(`\bBuild fingerprint: '(.*?)'`)
+	tombstoneTimestamp = regexp.MustCompile(`\bTimestamp: (.*?)\s*$`)
+	tombstoneBacktrace = regexp.MustCompile(`\bbacktrace:\s*$`)
+	tombstoneFrame     = regexp.MustCompile(`#(\d+) pc ([0-9a-f]+)\s+(\S+)(?:\s+\((.*?)\))?(?:\s+\(BuildId: \w+\))?\s*$`)
+)
+
+// Tombstone is a native crash dumped by debuggerd, from a tombstone file or from the DEBUG lines of logcat
+type Tombstone struct {
+	Pid          string
+	Tid          string
+	Thread       string
+	Process      string
+	Signal       string
+	Code         string
+	FaultAddr    string
+	Cause        string
+	AbortMessage string
+	Fingerprint  string
+	Timestamp    string
+	Frames       []NativeFrame
+	Line         int
+	End          int
+}
+
+// NativeFrame is a backtrace frame, Library is the file name of the shared object
+type NativeFrame struct {
+	Number   string
+	Pc       string
+	Path     string
+	Library  string
+	Function string
+}
+
+// CrashLibrary is the library of the first frame outside of libc, where the crash most likely comes from
+func (tombstone Tombstone) CrashLibrary() string {
+	for _, frame := range tombstone.Frames {
+		if frame.Library != "libc.so" {
+			return frame.Library
+		}
+	}
+	return ""
+}
+
+// parseTombstones reads the native crashes of the log. With a Pid pattern, the lines of other processes are skipped
+func parseTombstones(matchers *matcherSet, log *logLines) []Tombstone {
+	tombstones := []Tombstone{}
+	for index := 0; index < len(log.lines); index++ {
+		if !tombstoneStart.MatchString(log.lines[index]) {
+			continue
+		}
+		tombstone, end := readTombstone(matchers, log, index)
+		if tombstone.Signal == "" && tombstone.AbortMessage == "" && len(tombstone.Frames) == 0 {
+			continue
+		}
+		tombstones = append(tombstones, tombstone)
+		index = end - 1
+	}
+	return tombstones
+}
+
+// readTombstone returns the crash that starts at index and the index of the line that follows it
+func readTombstone(matchers *matcherSet, log *logLines, index int) (Tombstone, int) {
+	tombstone := Tombstone{Line: index + 1, End: index + 1}
+	pid := linePid(matchers, log.lines[index])
+	in_backtrace, skipped := false, 0
+	line := index + 1
+	for ; line < len(log.lines) && skipped <= maxTraceInterleaved; line++ {
+		content := log.lines[line]
+		if line_pid := linePid(matchers, content); pid != "" && line_pid != "" && line_pid != pid {
+			skipped++
+			continue
+		}
+		if tombstoneStart.MatchString(content) || (!in_backtrace && line-index > maxTombstoneHeaderLines) {
+			break
+		}
+		skipped = 0
+		if in_backtrace {
+			frame := tombstoneFrame.FindStringSubmatch(content)
+			if frame == nil {
+				//The backtrace ends the summary, the stack and memory dumps that follow are not read
+				if len(tombstone.Frames) > 0 {
+					break
+				}
+				continue
+			}
+			if len(tombstone.Frames) < maxTombstoneFrames {
+				tombstone.Frames = append(tombstone.Frames, NativeFrame{Number: frame[1], Pc: frame[2], Path: frame[3], Library: path.Base(frame[3]), Function: frame[4]})
+			}
+		} else if process := tombstoneProcess.FindStringSubmatch(content); process != nil && tombstone.Pid == "" {
+			tombstone.Pid, tombstone.Tid, tombstone.Thread, tombstone.Process = process[1], process[2], process[3], process[4]
+		} else if signal := tombstoneSignal.FindStringSubmatch(content); signal != nil && tombstone.Signal == "" {
+			tombstone.Signal = signal[1] + " (" + signal[2] + ")"
+			tombstone.Code = signal[3] + " (" + signal[4] + ")"
+			tombstone.FaultAddr = signal[5]
+		} else if abort := tombstoneAbort.FindStringSubmatch(content); abort != nil && tombstone.AbortMessage == "" {
+			tombstone.AbortMessage = strings.TrimSuffix(abort[1], "'")
+		} else if cause := tombstoneCause.FindStringSubmatch(content); cause != nil && tombstone.Cause == "" {
+			tombstone.Cause = cause[1]
+		} else if build := tombstoneBuild.FindStringSubmatch(content); build != nil {
+			tombstone.Fingerprint = build[1]
+		} else if timestamp := tombstoneTimestamp.FindStringSubmatch(content); timestamp != nil && tombstone.Timestamp == "" {
+			tombstone.Timestamp = timestamp[1]
+		} else if tombstoneBacktrace.MatchString(content) {
+			in_backtrace = true
+		}
+		tombstone.End = line + 1
+	}
+	return tombstone, tombstone.End
+}
@@ -0,0 +1,85 @@
+package report
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const tombstoneLogcat = `10-19 14:10:02.120  4980  5011 F libc    : Fatal signal 11 (SIGSEGV), code 1 (SEGV_MAPERR), fault addr 0x10 in tid 5011 (RenderThread), pid 4980 (com.example.app)
+10-19 14:10:02.331  5120  5120 F DEBUG   : *** *** *** *** *** *** *** *** *** *** *** *** *** *** *** ***
+10-19 14:10:02.331  5120  5120 F DEBUG   : Build fingerprint: 'google/sunfish/sunfish:11/RQ3A.210805.001.A1/7474174:user/release-keys'
+10-19 14:10:02.331  5120  5120 F DEBUG   : Revision: 'MP1.0'
+10-19 14:10:02.331  5120  5120 F DEBUG   : ABI: 'arm64'
+10-19 14:10:02.332  5120  5120 F DEBUG   : Timestamp: 2026-10-19 14:10:02+0200
+10-19 14:10:02.332  5120  5120 F DEBUG   : pid: 4980, tid: 5011, name: RenderThread  >>> com.example.app <<<
+10-19 14:10:02.332  5120  5120 F DEBUG   : uid: 10245
+10-19 14:10:02.332  5120  5120 F DEBUG   : signal 11 (SIGSEGV), code 1 (SEGV_MAPERR), fault addr 0x0000000000000010
+10-19 14:10:02.332  5120  5120 F DEBUG   : Cause: null pointer dereference
+10-19 14:10:02.332  1021  1044 I ActivityManager: Process com.example.app (pid 4980) has died: fg  TOP
+10-19 14:10:02.332  5120  5120 F DEBUG   :     x0  0000000000000000  x1  0000007b3c2a1e40  x2  0000000000000001  x3  0000000000000000
+10-19 14:10:02.333  5120  5120 F DEBUG   : backtrace:
+10-19 14:10:02.333  5120  5120 F DEBUG   :       #00 pc 000000000004f1a8  /data/app/com.example.app-1/lib/arm64/libnative-render.so (Renderer::draw(Frame*)+56) (BuildId: 4f3c2b1a)
+10-19 14:10:02.333  5120  5120 F DEBUG   :       #01 pc 000000000004e0c4  /data/app/com.example.app-1/lib/arm64/libnative-render.so (Renderer::onFrame()+212)
+10-19 14:10:02.333  5120  5120 F DEBUG   :       #02 pc 00000000000d6a0c  /apex/com.android.runtime/lib64/bionic/libc.so (__pthread_start(void*)+64) (BuildId: 2d1e8b7c)
+10-19 14:10:02.334  5120  5120 F DEBUG   :       #03 pc 0000000000070e0c  /apex/com.android.runtime/lib64/bionic/libc.so (__start_thread+64)
+10-19 14:10:02.334  5120  5120 F DEBUG   : 
+10-19 14:10:02.334  5120  5120 F DEBUG   : stack:
+10-19 14:10:02.334  5120  5120 F DEBUG   :          0000007b3c2a1d00  0000000000000000
+10-19 14:12:30.002  6050  6050 F DEBUG   : *** *** *** *** *** *** *** *** *** *** *** *** *** *** *** ***
+10-19 14:12:30.002  6050  6050 F DEBUG   : pid: 6001, tid: 6001, name: example.worker  >>> com.example.worker <<<
+10-19 14:12:30.002  6050  6050 F DEBUG   : signal 6 (SIGABRT), code -1 (SI_QUEUE), fault addr --------
+10-19 14:12:30.002  6050  6050 F DEBUG   : Abort message: 'Check failed: ptr != nullptr'
+10-19 14:12:30.003  6050  6050 F DEBUG   : backtrace:
+10-19 14:12:30.003  6050  6050 F DEBUG   :       #00 pc 000000000004e8bc  /apex/com.android.runtime/lib64/bionic/libc.so (abort+164) (BuildId: 2d1e8b7c)
+10-19 14:12:30.003  6050  6050 F DEBUG   :       #01 pc 0000000000012a40  /system/lib64/libworker.so (Worker::run()+96)
+10-19 14:12:30.003  6050  6050 F DEBUG   :       #02 pc 0000000000011f00  /system/lib64/libworker.so
+10-19 14:13:00.000  6100  6100 F DEBUG   : *** *** *** *** *** *** *** *** *** *** *** *** *** *** *** ***
+10-19 14:13:00.000  6100  6100 F DEBUG   : Revision: 'MP1.0'`
+
+func TestParseTombstonesFromLogcat(t *testing.T) {
+	cfg := testConfig(t, `
+IssuesGeneralFields:
+  Pid: '^\S+ \S+\s+(\d+)'
+`)
+	tombstones := parseTombstones(cfg.matchers, splitLines("tombstones.txt", tombstoneLogcat))
+	if len(tombstones) != 2 {
+		t.Fatalf("%d tombstones, a header without a crash is skipped: %+v", len(tombstones), tombstones)
+	}
+	segv := tombstones[0]
+	segv_frames := segv.Frames
+	segv.Frames = nil
+	expected := Tombstone{
+		Pid: "4980", Tid: "5011", Thread: "RenderThread", Process: "com.example.app",
+		Signal: "11 (SIGSEGV)", Code: "1 (SEGV_MAPERR)", FaultAddr: "0x0000000000000010", Cause: "null pointer dereference",
+		Fingerprint: "google/sunfish/sunfish:11/RQ3A.210805.001.A1/7474174:user/release-keys", Timestamp: "2026-10-19 14:10:02+0200",
+		Line: 2, End: 17,
+	}
+	if !reflect.DeepEqual(segv, expected) {
+		t.Errorf("tombstone %+v\nexpected %+v", segv, expected)
+	}
+	if len(segv_frames) != 4 || segv_frames[0] != (NativeFrame{"00", "000000000004f1a8", "/data/app/com.example.app-1/lib/arm64/libnative-render.so", "libnative-render.so", "Renderer::draw(Frame*)+56"}) || segv_frames[3].Function != "__start_thread+64" {
+		t.Errorf("frames %+v", segv_frames)
+	}
+	abort := tombstones[1]
+	if abort.AbortMessage != "Check failed: ptr != nullptr" || abort.Signal != "6 (SIGABRT)" || abort.Code != "-1 (SI_QUEUE)" || abort.FaultAddr != "--------" || abort.Line != 21 || abort.End != 28 {
+		t.Errorf("abort %+v", abort)
+	}
+	if libraries := []string{tombstones[0].CrashLibrary(), abort.CrashLibrary()}; !reflect.DeepEqual(libraries, []string{"libnative-render.so", "libworker.so"}) || abort.Frames[2].Function != "" {
+		t.Errorf("crash libraries %q, frames %+v", libraries, abort.Frames)
+	}
+}
+
+// The backtrace of a tombstone file is read without a Pid pattern, and ends the summary
+func TestParseTombstoneFile(t *testing.T) {
+	lines := []string{}
+	for _, line := range strings.Split(tombstoneLogcat, "\n")[1:20] {
+		if !strings.Contains(line, "ActivityManager") {
+			lines = append(lines, line[strings.Index(line, ": ")+2:])
+		}
+	}
+	tombstones := parseTombstones(testConfig(t, "").matchers, splitLines("tombstone_04", strings.Join(lines, "\n")))
+	if len(tombstones) != 1 || tombstones[0].Pid != "4980" || len(tombstones[0].Frames) != 4 || tombstones[0].End != 15 {
+		t.Fatalf("%+v", tombstones)
+	}
+}
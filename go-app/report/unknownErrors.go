@@ -0,0 +1,106 @@
+package report
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type UnknownError struct {
+	Template string
+	Count    int
+	Examples []string
+	First    string
+	Last     string
+}
+
+const (
+	maxUnknownErrors   = 100
+	maxUnknownExamples = 3
+)
+
+var (
+	//Order matters: the most specific tokens are masked first
+	variableTokens = []struct {
+		rgx     *regexp.Regexp
+		mask    string
+		pattern string
+	}{
+		{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), "<UUID>", `[0-9a-fA-F-]{36}`},
+		{regexp.MustCompile(`(?:[A-Za-z]:)?(?:/[\w.@-]+){2,}/?`), "<PATH>", `\S+`},
+		{regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b|\b[0-9a-f]*(?:[0-9][0-9a-f]*[a-f]|[a-f][0-9a-f]*[0-9])[0-9a-f]*\b`), "<HEX>", `(?:0x)?[0-9a-fA-F]+`},
+		{regexp.MustCompile(`[-+]?\d+(?:\.\d+)?`), "<NUM>", `[-+]?\d+(?:\.\d+)?`},
+	}
+)
+
+// Cluster the error lines that none of the configured issues matched, their levels are LogLevels.Errors
+func clusterUnknownErrors(cfgFile *Config, log *logLines, claimed_logs map[int]bool) []UnknownError {
+	level_rgx := cfgFile.matchers.level
+	if level_rgx == nil {
+		return nil
+	}
+	timestamp_rgx := cfgFile.matchers.timestamp
+	clusters := make(map[string]*UnknownError)
+	for index, line := range log.lines {
+		if claimed_logs[index] || !cfgFile.matchers.isErrorLevel(line) {
+			continue
+		}
+		//The template is the message after the level
+		loc := level_rgx.FindStringIndex(line)
+		template := maskVariableTokens(strings.TrimSpace(line[loc[1]:]))
+		cluster, ok := clusters[template]
+		if !ok {
+			cluster = &UnknownError{Template: template}
+			clusters[template] = cluster
+		}
+		cluster.Count++
+		if len(cluster.Examples) < maxUnknownExamples && !containsLog(cluster.Examples, line) {
+			cluster.Examples = append(cluster.Examples, line)
+		}
+		if timestamp_rgx != nil {
+			if timestamp := timestamp_rgx.FindString(line); timestamp != "" {
+				if cluster.First == "" {
+					cluster.First = timestamp
+				}
+				cluster.Last = timestamp
+			}
+		}
+	}
+	unknown_errors := make([]UnknownError, 0, len(clusters))
+	for _, cluster := range clusters {
+		unknown_errors = append(unknown_errors, *cluster)
+	}
+	sort.Slice(unknown_errors, func(i, j int) bool {
+		if unknown_errors[i].Count != unknown_errors[j].Count {
+			return unknown_errors[i].Count > unknown_errors[j].Count
+		}
+		return unknown_errors[i].Template < unknown_errors[j].Template
+	})
+	if len(unknown_errors) > maxUnknownErrors {
+		unknown_errors = unknown_errors[:maxUnknownErrors]
+	}
+	return unknown_errors
+}
+func maskVariableTokens(message string) string {
+	for _, token := range variableTokens {
+		message = token.rgx.ReplaceAllString(message, token.mask)
+	}
+	return message
+}
+func containsLog(logs []string, log string) bool {
+	for _, l := range logs {
+		if l == log {
+			return true
+		}
+	}
+	return false
+}
+
+// TemplateRegex turns a clustered template back into an issue regex
+func TemplateRegex(template string) string {
+	rgx := regexp.QuoteMeta(template)
+	for _, token := range variableTokens {
+		rgx = strings.Replace(rgx, token.mask, token.pattern, -1)
+	}
+	return ".*" + rgx + ".*"
+}
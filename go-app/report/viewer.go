@@ -0,0 +1,139 @@
+package report
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	defaultRangeCount = 200
+	maxRangeCount     = 2000
+)
+
+// LineRange is one page of the raw log viewer, Total counts the lines that pass the filter
+type LineRange struct {
+	Start int
+	Total int
+	Lines []RangeLine
+}
+type RangeLine struct {
+	Number  int
+	Content string
+	Level   string
+	Notes   []string
+}
+
+// lineFilter keeps the lines of one level and of one process, an empty field is not filtered
+type lineFilter struct {
+	level   string
+	process string
+	pid     string
+}
+
+// filterCache keeps the lines that passed the last filter, so that scrolling does not filter the whole log again
+var filterCache struct {
+	mutex    sync.Mutex
+	id       string
+	matchers *matcherSet
+	filter   lineFilter
+	log      *logLines
+	lines    []int
+}
+
+// lineRange returns count lines of the filtered log, from the start-th line that passes the filter
+func lineRange(fullLogDetails *FullDetails, matchers *matcherSet, filter lineFilter, start int, count int) (LineRange, error) {
+	if count <= 0 || count > maxRangeCount {
+		count = defaultRangeCount
+	}
+	if start < 0 {
+		start = 0
+	}
+	log, lines, err := filteredLines(fullLogDetails, matchers, filter)
+	if err != nil {
+		return LineRange{}, err
+	}
+	line_range := LineRange{Start: start, Total: len(log.lines), Lines: []RangeLine{}}
+	if lines != nil {
+		line_range.Total = len(lines)
+	}
+	for position := start; position < start+count && position < line_range.Total; position++ {
+		index := position
+		if lines != nil {
+			index = lines[position]
+		}
+		line := RangeLine{Number: index + 1, Content: log.lines[index]}
+		if matchers != nil {
+			line.Level = matchers.lineLevel(line.Content)
+		}
+		if notes := fullLogDetails.Analysis_details.Notes.ForLine(line.Number); len(notes) > 0 {
+			line.Notes = noteTexts(notes)
+		}
+		line_range.Lines = append(line_range.Lines, line)
+	}
+	return line_range, nil
+}
+
+// filteredLines returns the log and the indexes of the lines that pass the filter, nil when nothing is filtered
+func filteredLines(fullLogDetails *FullDetails, matchers *matcherSet, filter lineFilter) (*logLines, []int, error) {
+	filterCache.mutex.Lock()
+	defer filterCache.mutex.Unlock()
+	id := fullLogDetails.Analysis_details.Id
+	if filterCache.log == nil || filterCache.id != id {
+		filterCache.log = splitLines(fullLogDetails.Analysis_details.FileName, fullLogDetails.Analysis_details.RawLog)
+		filterCache.id = id
+		filterCache.matchers = nil
+	} else if filterCache.matchers == matchers && filterCache.filter == filter {
+		return filterCache.log, filterCache.lines, nil
+	}
+	log := filterCache.log
+	if filter == (lineFilter{}) {
+		return log, nil, nil
+	}
+	keep, err := compileLineFilter(log, matchers, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	lines := []int{}
+	for index, line := range log.lines {
+		if keep(index, line) {
+			lines = append(lines, index)
+		}
+	}
+	filterCache.matchers, filterCache.filter, filterCache.lines = matchers, filter, lines
+	return log, lines, nil
+}
+
+// compileLineFilter uses the LogLevels and Pid patterns of the config. Processes are the SpecificProcess of the config
+func compileLineFilter(log *logLines, matchers *matcherSet, filter lineFilter) (func(int, string) bool, error) {
+	level_match := func(string) bool { return true }
+	if filter.level != "" {
+		if matchers == nil || matchers.level == nil {
+			return nil, fmt.Errorf("level: needs a LogLevels pattern in the config")
+		}
+		level_match = func(line string) bool { return matchers.isLevel(line, filter.level) }
+	}
+	pid_match := func(string) bool { return true }
+	if filter.pid != "" {
+		if matchers == nil || matchers.pid == nil {
+			return nil, fmt.Errorf("pid: needs a Pid pattern in the config")
+		}
+		pid_match = func(line string) bool { return captureField(matchers.pid, line) == filter.pid }
+	}
+	var process_lines map[int]bool
+	if filter.process != "" {
+		if matchers == nil {
+			return nil, fmt.Errorf("No config loaded")
+		}
+		proc_matcher, ok := matchers.specific_process[filter.process]
+		if !ok {
+			return nil, fmt.Errorf("Unknown process %q", filter.process)
+		}
+		process_lines = make(map[int]bool)
+		for _, index := range matchedLines(proc_matcher.matchLines(log, nil, matchers.scan(log))) {
+			process_lines[index] = true
+		}
+	}
+	return func(index int, line string) bool {
+		return (process_lines == nil || process_lines[index]) && level_match(line) && pid_match(line)
+	}, nil
+}
@@ -12,6 +12,7 @@ import (
 
 	"cloud.google.com/go/storage"
 	"github.com/PuerkitoBio/goquery"
+	"gopkg.in/yaml.v2"
 )
 
 func UploadConfigFile(r *http.Request, project_id string, cloudConfigs map[string][]string) (map[string][]string, error) {
@@ -153,3 +154,46 @@ func DisplayConfig(w http.ResponseWriter, r *http.Request, project_id string, re
 	return selectedBucket, cfgfile, string(content), err
 
 }
+
+// AddConfigIssue appends an issue made from an unknown error template to a config
+func AddConfigIssue(bucket string, cfgfile string, issue_name string, issue_regex string) error {
+	if bucket == "" || cfgfile == "" {
+		return errors.New("No configuration selected")
+	}
+	if issue_name == "" {
+		return errors.New("Missing issue name")
+	}
+	content, err := utilities.DownloadFile(nil, bucket, cfgfile)
+	if err != nil {
+		return err
+	}
+	cfg := yaml.MapSlice{}
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		return err
+	}
+	//Issues only look in the lines of their specific processes, the unknown errors come from the whole log
+	whole_log := yaml.MapSlice{{Key: "whole_log", Value: "(?m)^.*$"}}
+	new_issue := yaml.MapItem{Key: issue_name, Value: yaml.MapSlice{{Key: "regex", Value: issue_regex}, {Key: "specific_process", Value: whole_log}}}
+	found := false
+	for index, section := range cfg {
+		if section.Key != "Issues" {
+			continue
+		}
+		issues, _ := section.Value.(yaml.MapSlice)
+		for _, issue := range issues {
+			if issue.Key == issue_name {
+				return fmt.Errorf("Issue %q already exists in %s", issue_name, cfgfile)
+			}
+		}
+		cfg[index].Value = append(issues, new_issue)
+		found = true
+	}
+	if !found {
+		cfg = append(cfg, yaml.MapItem{Key: "Issues", Value: yaml.MapSlice{new_issue}})
+	}
+	newContent, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	return utilities.UploadFile(bucket, cfgfile, newContent)
+}
@@ -83,3 +83,68 @@ func GetConfigFiles(bucket string) ([]string, error) {
 	}
 	return configs, nil
 }
+
+func GetObjectGeneration(bucket, object string) (int64, error) {
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("storage.NewClient: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	attrs, err := client.Bucket(bucket).Object(object).Attrs(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("Object(%q).Attrs: %v", object, err)
+	}
+	return attrs.Generation, nil
+}
+
+// DownloadFileIfExists returns false, and no error, when the object does not exist yet
+func DownloadFileIfExists(bucket, object string) ([]byte, bool, error) {
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("storage.NewClient: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*50)
+	defer cancel()
+	rc, err := client.Bucket(bucket).Object(object).NewReader(ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("Object(%q).NewReader: %v", object, err)
+	}
+	defer rc.Close()
+
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, false, fmt.Errorf("ioutil.ReadAll: %v", err)
+	}
+	return data, true, nil
+}
+
+// UploadFile replaces the content of an object, creating it when needed
+func UploadFile(bucket, object string, content []byte) error {
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("storage.NewClient: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	wc := client.Bucket(bucket).Object(object).NewWriter(ctx)
+	if _, err := wc.Write(content); err != nil {
+		return fmt.Errorf("Object(%q).Write: %v", object, err)
+	}
+	if err := wc.Close(); err != nil {
+		return fmt.Errorf("Object(%q).Close: %v", object, err)
+	}
+	return nil
+}